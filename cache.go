@@ -0,0 +1,296 @@
+package gojob
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores the outcomes of a stage for WithCache, keyed by K. It must be
+// safe for concurrent use.
+type Cache[K comparable, V any] interface {
+	// Get returns the entry stored for key, if any, expired or not.
+	Get(key K) (CacheEntry[V], bool, error)
+	// Set stores e for key, replacing any earlier entry.
+	Set(key K, e CacheEntry[V]) error
+}
+
+// CacheEntry is a cached outcome: a Value, or the message of a cached failure
+// (see WithNegativeCache). A zero Expires never expires.
+type CacheEntry[V any] struct {
+	Value   V         `json:"value"`
+	Err     string    `json:"error,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// WithCache memoizes the stage: before running fn on an input, the stage looks
+// up key(input) in cache, and an unexpired entry becomes the Result directly,
+// with Cached set and no attempts. Otherwise fn runs as usual (with its
+// retries) and a success is stored for ttl (zero keeps it indefinitely);
+// workers that miss on a key another worker is already running wait for its
+// outcome instead of running fn again. Use an LRUCache within a run, or a
+// FileCache to carry results across reruns:
+//
+//	cache, err := gojob.NewFileCache[Geo]("cache/geo")
+//	...
+//	results := gojob.Process(ctx, ips, lookup,
+//		gojob.WithCache(cache, func(ip string) string { return ip }, 24*time.Hour))
+//
+// Cache errors are logged and treated as misses. The stage's input and output
+// types must match In and V, or Process panics.
+func WithCache[In any, K comparable, V any](cache Cache[K, V], key func(In) K, ttl time.Duration) Option {
+	return func(c *config) {
+		c.cache = &cacheMemo[In, K, V]{cache: cache, key: key, ttl: ttl}
+	}
+}
+
+// WithNegativeCache also caches failures for ttl — usually shorter than that
+// of WithCache — so inputs that are known to fail are not retried on every
+// occurrence. Cancellations and timeouts are never cached. A cached failure
+// carries the original error's message only.
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(c *config) {
+		c.negativeTTL = ttl
+	}
+}
+
+// memo is the cache of a stage, as seen by the worker pool.
+type memo[In, Out any] interface {
+	// load returns the cached Result for input or, on a miss, the outcome of
+	// run, which it caches as configured.
+	load(ctx context.Context, input In, negativeTTL time.Duration, run func() Result[Out]) Result[Out]
+}
+
+// memoFor returns the memo configured with WithCache for a stage from In to
+// Out, or nil.
+func memoFor[In, Out any](cfg config) memo[In, Out] {
+	if cfg.cache == nil {
+		return nil
+	}
+	m, ok := cfg.cache.(memo[In, Out])
+	if !ok {
+		var in In
+		var out Out
+		panic(fmt.Sprintf("gojob: WithCache does not match a stage from %T to %T", in, out))
+	}
+	return m
+}
+
+type cacheMemo[In any, K comparable, V any] struct {
+	cache Cache[K, V]
+	key   func(In) K
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	running map[K]chan struct{} // closed once the key's run is cached
+}
+
+// load collapses concurrent misses on one key: the first runs, and the others
+// wait for it and then look again, so they share its outcome whenever it was
+// cached. An outcome that is not cached, such as a failure without
+// WithNegativeCache, leaves each of them to run on its own.
+func (m *cacheMemo[In, K, V]) load(ctx context.Context, input In, negativeTTL time.Duration, run func() Result[V]) Result[V] {
+	k := m.key(input)
+	for {
+		if r, ok := m.get(k); ok {
+			return r
+		}
+		m.mu.Lock()
+		done, busy := m.running[k]
+		if !busy {
+			if m.running == nil {
+				m.running = map[K]chan struct{}{}
+			}
+			done = make(chan struct{})
+			m.running[k] = done
+		}
+		m.mu.Unlock()
+		if !busy {
+			r := run()
+			m.put(k, r, negativeTTL)
+			m.mu.Lock()
+			delete(m.running, k)
+			m.mu.Unlock()
+			close(done)
+			return r
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return Result[V]{Err: ctx.Err(), StartedAt: time.Now()}
+		}
+	}
+}
+
+func (m *cacheMemo[In, K, V]) get(k K) (Result[V], bool) {
+	e, ok, err := m.cache.Get(k)
+	if err != nil {
+		slog.Warn("gojob: cache lookup failed", slog.String("error", err.Error()))
+		return Result[V]{}, false
+	}
+	if !ok || (!e.Expires.IsZero() && time.Now().After(e.Expires)) {
+		return Result[V]{}, false
+	}
+	r := Result[V]{Value: e.Value, StartedAt: time.Now(), Cached: true}
+	if e.Err != "" {
+		r.Err = errors.New(e.Err)
+	}
+	return r, true
+}
+
+func (m *cacheMemo[In, K, V]) put(k K, r Result[V], negativeTTL time.Duration) {
+	e := CacheEntry[V]{Value: r.Value}
+	ttl := m.ttl
+	if r.Err != nil {
+		if negativeTTL <= 0 || errors.Is(r.Err, context.Canceled) || errors.Is(r.Err, context.DeadlineExceeded) {
+			return
+		}
+		var zero V
+		e = CacheEntry[V]{Value: zero, Err: r.Err.Error()}
+		ttl = negativeTTL
+	}
+	if ttl > 0 {
+		e.Expires = time.Now().Add(ttl)
+	}
+	if err := m.cache.Set(k, e); err != nil {
+		slog.Warn("gojob: cache update failed", slog.String("error", err.Error()))
+	}
+}
+
+// resultMemo adapts a memo over values to Then, whose workers receive the
+// Results of the previous stage.
+type resultMemo[In, Out any] struct{ memo[In, Out] }
+
+func (m resultMemo[In, Out]) load(ctx context.Context, r Result[In], negativeTTL time.Duration, run func() Result[Out]) Result[Out] {
+	return m.memo.load(ctx, r.Value, negativeTTL, run)
+}
+
+// LRUCache is an in-memory Cache that holds up to a fixed number of entries,
+// evicting the least recently used.
+type LRUCache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *lruEntry, most recently used first
+	items map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	entry CacheEntry[V]
+}
+
+// NewLRUCache returns an LRUCache holding up to size entries.
+func NewLRUCache[K comparable, V any](size int) *LRUCache[K, V] {
+	return &LRUCache[K, V]{size: max(size, 1), order: list.New(), items: map[K]*list.Element{}}
+}
+
+// Get implements Cache.
+func (c *LRUCache[K, V]) Get(key K) (CacheEntry[V], bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return CacheEntry[V]{}, false, nil
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry[K, V]).entry, true, nil
+}
+
+// Set implements Cache.
+func (c *LRUCache[K, V]) Set(key K, e CacheEntry[V]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry[K, V]).entry = e
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, entry: e})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries held.
+func (c *LRUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// FileCache is a Cache that keeps every entry as a JSON file in a directory,
+// so cached results survive across runs. Entries are not removed when they
+// expire, only replaced; delete the directory to clear it.
+type FileCache[V any] struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache in dir, creating the directory if needed.
+func NewFileCache[V any](dir string) (*FileCache[V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCache[V]{dir: dir}, nil
+}
+
+// path is the file of key: its SHA-256, fanned out over 256 subdirectories.
+func (c *FileCache[V]) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name[2:]+".json")
+}
+
+// Get implements Cache.
+func (c *FileCache[V]) Get(key string) (CacheEntry[V], bool, error) {
+	var e CacheEntry[V]
+	b, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return e, false, nil
+	}
+	if err != nil {
+		return e, false, err
+	}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return e, false, fmt.Errorf("gojob: cache entry for %q: %w", key, err)
+	}
+	return e, true, nil
+}
+
+// Set implements Cache. The entry is written to a temporary file and renamed
+// into place, so concurrent readers never see a partial entry.
+func (c *FileCache[V]) Set(key string, e CacheEntry[V]) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,192 @@
+package gojob_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+func identity(s string) string { return s }
+
+func TestWithCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int64
+	upper := func(ctx context.Context, s string) (string, error) {
+		calls.Add(1)
+		return strings.ToUpper(s), nil
+	}
+	cache := gojob.NewLRUCache[string, string](10)
+	results := collect(gojob.Process(ctx, gojob.From(ctx, "a", "b", "a", "a"), upper,
+		gojob.WithCache(cache, identity, time.Hour)))
+	if calls.Load() != 2 {
+		t.Errorf("fn ran %d times, want 2", calls.Load())
+	}
+	hits := 0
+	for _, r := range results {
+		if (r.Value != "A" && r.Value != "B") || r.Err != nil {
+			t.Errorf("unexpected result %+v", r)
+		}
+		if r.Cached {
+			hits++
+			if r.Attempts != 0 {
+				t.Errorf("a cache hit made %d attempts", r.Attempts)
+			}
+		}
+	}
+	if hits != 2 {
+		t.Errorf("%d cache hits, want 2", hits)
+	}
+}
+
+func TestWithCacheConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int64
+	slow := func(ctx context.Context, s string) (string, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return strings.ToUpper(s), nil
+	}
+	keys := strings.Split(strings.Repeat("k", 8), "")
+	results := collect(gojob.Process(ctx, gojob.From(ctx, keys...), slow,
+		gojob.WithWorkers(8), gojob.WithCache(gojob.NewLRUCache[string, string](10), identity, time.Hour)))
+	if calls.Load() != 1 {
+		t.Errorf("fn ran %d times for one key, want 1", calls.Load())
+	}
+	hits := 0
+	for _, r := range results {
+		if r.Value != "K" || r.Err != nil {
+			t.Errorf("unexpected result %+v", r)
+		}
+		if r.Cached {
+			hits++
+		}
+	}
+	if hits != 7 {
+		t.Errorf("%d results shared the first run's outcome, want 7", hits)
+	}
+}
+
+func TestWithCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache := gojob.NewLRUCache[string, string](10)
+	_ = cache.Set("old", gojob.CacheEntry[string]{Value: "stale", Expires: time.Now().Add(-time.Second)})
+	_ = cache.Set("new", gojob.CacheEntry[string]{Value: "fresh"})
+	results := collect(gojob.Process(ctx, gojob.From(ctx, "old", "new"), func(ctx context.Context, s string) (string, error) {
+		return "computed", nil
+	}, gojob.WithCache(cache, identity, time.Hour)))
+	got := map[string]bool{}
+	for _, r := range results {
+		got[r.Value] = r.Cached
+	}
+	if cached, ok := got["computed"]; !ok || cached {
+		t.Errorf("an expired entry should be recomputed: %v", got)
+	}
+	if cached, ok := got["fresh"]; !ok || !cached {
+		t.Errorf("an entry without expiry should be a hit: %v", got)
+	}
+	if e, _, _ := cache.Get("old"); e.Value != "computed" || e.Expires.IsZero() {
+		t.Errorf("the recomputed entry should replace the expired one: %+v", e)
+	}
+}
+
+func TestWithNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	for _, negative := range []bool{false, true} {
+		var calls atomic.Int64
+		opts := []gojob.Option{gojob.WithCache(gojob.NewLRUCache[string, int](10), identity, time.Hour)}
+		if negative {
+			opts = append(opts, gojob.WithNegativeCache(time.Minute))
+		}
+		results := collect(gojob.Process(ctx, gojob.From(ctx, "x", "x", "x"), func(ctx context.Context, s string) (int, error) {
+			calls.Add(1)
+			return 0, errors.New("not found")
+		}, opts...))
+		want := int64(3)
+		if negative {
+			want = 1
+		}
+		if calls.Load() != want {
+			t.Errorf("negative=%v: fn ran %d times, want %d", negative, calls.Load(), want)
+		}
+		for _, r := range results {
+			if r.Err == nil || r.Err.Error() != "not found" {
+				t.Errorf("negative=%v: want the original error, got %+v", negative, r)
+			}
+		}
+	}
+}
+
+func TestFileCacheAcrossRuns(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var calls atomic.Int64
+	run := func() []gojob.Result[int] {
+		cache, err := gojob.NewFileCache[int](dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return collect(gojob.Process(ctx, gojob.From(ctx, "a", "bb"), func(ctx context.Context, s string) (int, error) {
+			calls.Add(1)
+			return len(s), nil
+		}, gojob.WithCache(cache, identity, 0), gojob.WithWorkers(2)))
+	}
+	run()
+	second := run()
+	if calls.Load() != 2 {
+		t.Errorf("fn ran %d times over two runs, want 2", calls.Load())
+	}
+	for _, r := range second {
+		if !r.Cached || (r.Value != 1 && r.Value != 2) {
+			t.Errorf("second run: %+v", r)
+		}
+	}
+	b, _ := json.Marshal(second[0])
+	if !strings.Contains(string(b), `"cached":true`) {
+		t.Errorf("JSON should mark the hit: %s", b)
+	}
+}
+
+func TestLRUCacheEvicts(t *testing.T) {
+	c := gojob.NewLRUCache[int, int](2)
+	_ = c.Set(1, gojob.CacheEntry[int]{Value: 1})
+	_ = c.Set(2, gojob.CacheEntry[int]{Value: 2})
+	_, _, _ = c.Get(1) // 2 is now the least recently used
+	_ = c.Set(3, gojob.CacheEntry[int]{Value: 3})
+	if _, ok, _ := c.Get(2); ok {
+		t.Error("2 should have been evicted")
+	}
+	if _, ok, _ := c.Get(1); !ok || c.Len() != 2 {
+		t.Errorf("1 should be kept, len = %d", c.Len())
+	}
+}
+
+func TestWithCacheThen(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int64
+	cache := gojob.NewLRUCache[int, int](10)
+	first := gojob.Process(ctx, gojob.From(ctx, 2, 2, 3), func(ctx context.Context, n int) (int, error) { return n, nil })
+	results := collect(gojob.Then(ctx, first, func(ctx context.Context, n int) (int, error) {
+		calls.Add(1)
+		return n * n, nil
+	}, gojob.WithCache(cache, func(n int) int { return n }, 0)))
+	if len(results) != 3 || calls.Load() != 2 {
+		t.Errorf("got %d results from %d calls, want 3 from 2", len(results), calls.Load())
+	}
+}
+
+func TestWithCacheTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a cache of the wrong type should panic")
+		}
+	}()
+	ctx := context.Background()
+	gojob.Process(ctx, gojob.From(ctx, 1), func(ctx context.Context, n int) (int, error) { return n, nil },
+		gojob.WithCache(gojob.NewLRUCache[string, string](1), identity, 0))
+}
@@ -0,0 +1,179 @@
+// Command gojob bundles utilities for working with the output of gojob runs.
+//
+//	gojob merge -o merged.jsonl -k value.url -dedup -expect urls.txt shard-*.jsonl
+//
+// merge concatenates the JSONL outputs of a sharded run (see gojob.Shard),
+// optionally dropping duplicate records, and lists every input item that no
+// shard produced a result for. It exits with status 2 when items are missing,
+// so a shard that silently died fails the script that merges it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/WangYihang/gojob"
+	"github.com/WangYihang/gojob/pkg/version"
+	"github.com/WangYihang/uio"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gojob <command> [flags]\n\ncommands:\n  merge    merge sharded JSONL outputs and check input coverage\n  version  print version and exit\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	switch os.Args[1] {
+	case "merge":
+		os.Exit(merge(os.Args[2:]))
+	case "version", "-version", "--version":
+		fmt.Print(version.GetVersion())
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func merge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	var (
+		output  = fs.String("o", "-", "output file: merged JSONL ('-' = stdout; gzip/S3 via uio)")
+		key     = fs.String("k", "", "dotted path to each record's input key, e.g. value.url")
+		dedup   = fs.Bool("dedup", false, "keep only the first record per key (requires -k)")
+		expect  = fs.String("expect", "", "original input, one item per line; report items no shard covered (requires -k)")
+		missing = fs.String("m", "-", "where to list missing items ('-' = stderr)")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gojob merge [flags] shard.jsonl...\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	out, err := openOutput(*output, fs.Args())
+	if err != nil {
+		slog.Error("cannot open output", slog.String("path", *output), slog.String("error", err.Error()))
+		return 1
+	}
+
+	var opts []gojob.MergeOption
+	if *key != "" {
+		opts = append(opts, gojob.WithMergeKey(*key))
+	}
+	if *dedup {
+		opts = append(opts, gojob.WithDedup())
+	}
+	if *expect != "" {
+		opts = append(opts, gojob.WithExpected(*expect))
+	}
+	report, err := gojob.MergeJSONL(ctx, out, fs.Args(), opts...)
+	if err != nil {
+		out.abort()
+		slog.Error("merge failed", slog.String("error", err.Error()))
+		return 1
+	}
+	if err := out.commit(); err != nil {
+		slog.Error("cannot write output", slog.String("path", *output), slog.String("error", err.Error()))
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "merge: %d read, %d written, %d duplicates, %d missing\n",
+		report.Read, report.Written, report.Duplicates, len(report.Missing))
+
+	if len(report.Missing) == 0 {
+		return 0
+	}
+	w := os.Stderr
+	if *missing != "-" {
+		f, err := os.Create(*missing)
+		if err != nil {
+			slog.Error("cannot open missing list", slog.String("path", *missing), slog.String("error", err.Error()))
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	for _, item := range report.Missing {
+		fmt.Fprintln(w, item)
+	}
+	return 2
+}
+
+// output is where merge writes. A local file is written under a temporary
+// name and only replaces path once the merge succeeds, so a failed merge
+// leaves the previous output in place.
+type output struct {
+	io.WriteCloser
+	path, tmp string
+}
+
+// openOutput opens path for writing, refusing to overwrite any of inputs.
+func openOutput(path string, inputs []string) (*output, error) {
+	if path == "-" {
+		w, err := uio.Open(path)
+		return &output{WriteCloser: w, path: path}, err
+	}
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		uri := path + "?mode=write"
+		if u.RawQuery != "" {
+			uri = path + "&mode=write"
+		}
+		w, err := uio.Open(uri)
+		return &output{WriteCloser: w, path: path}, err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		for _, in := range inputs {
+			if ii, err := os.Stat(in); err == nil && os.SameFile(fi, ii) {
+				return nil, fmt.Errorf("%s is also an input", in)
+			}
+		}
+	}
+	// The temporary name keeps path's suffix, from which uio picks the
+	// compression. uio's write mode does not truncate local files.
+	tmp := filepath.Join(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	w, err := uio.Open(tmp + "?mode=write")
+	if err != nil {
+		return nil, err
+	}
+	return &output{WriteCloser: w, path: path, tmp: tmp}, nil
+}
+
+// commit closes the output and moves it into place.
+func (o *output) commit() error {
+	if err := o.Close(); err != nil {
+		if o.tmp != "" {
+			_ = os.Remove(o.tmp)
+		}
+		return err
+	}
+	if o.tmp == "" {
+		return nil
+	}
+	return os.Rename(o.tmp, o.path)
+}
+
+// abort closes the output and discards what was written to a local file.
+func (o *output) abort() {
+	_ = o.Close()
+	if o.tmp != "" {
+		_ = os.Remove(o.tmp)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeOutputIsInput(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.jsonl")
+	out := filepath.Join(dir, "merged.jsonl")
+	if err := os.WriteFile(a, []byte("{\"value\":1}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := merge([]string{"-o", out, a}); code != 0 {
+		t.Fatalf("first merge exited %d", code)
+	}
+	// Rerunning over a glob that now matches the output must not feed the
+	// output back into itself.
+	if code := merge([]string{"-o", out, a, out}); code != 1 {
+		t.Errorf("merge into one of its inputs exited %d, want 1", code)
+	}
+	// A merge that fails keeps the previous output.
+	if code := merge([]string{"-o", out, a, filepath.Join(dir, "typo.jsonl")}); code != 1 {
+		t.Errorf("merge of a missing shard exited %d, want 1", code)
+	}
+	if b, err := os.ReadFile(out); err != nil || string(b) != "{\"value\":1}\n" {
+		t.Errorf("output = %q (%v), want the first merge's", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".tmp-merged.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("temporary output left behind: %v", err)
+	}
+}
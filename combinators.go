@@ -0,0 +1,288 @@
+package gojob
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The combinators below are generic over the item type, so they apply to
+// sources (<-chan T) and to Process output (<-chan Result[T]) alike. Each runs
+// one goroutine, closes its output once its input is exhausted or ctx is
+// cancelled, and never blocks past cancellation.
+
+// recv receives from in unless ctx is cancelled first; ok is false in either
+// case of the stream being over.
+func recv[T any](ctx context.Context, in <-chan T) (v T, ok bool) {
+	select {
+	case v, ok = <-in:
+		return v, ok
+	case <-ctx.Done():
+		return v, false
+	}
+}
+
+// send sends v on out unless ctx is cancelled first.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Map applies fn to every item. For work that can fail, is slow, or should run
+// concurrently, use Process instead.
+func Map[In, Out any](ctx context.Context, in <-chan In, fn func(In) Out) <-chan Out {
+	out := make(chan Out)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok || !send(ctx, out, fn(v)) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Filter passes only the items for which pred returns true.
+func Filter[T any](ctx context.Context, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			if pred(v) && !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// FlatMap applies fn to every item and streams the items of the slices it
+// returns, in order.
+func FlatMap[In, Out any](ctx context.Context, in <-chan In, fn func(In) []Out) <-chan Out {
+	out := make(chan Out)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			for _, o := range fn(v) {
+				if !send(ctx, out, o) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Merge fans in several streams into one, in arrival order. The output closes
+// once all inputs have.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := recv(ctx, in)
+				if !ok || !send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Batch groups items into slices of size, emitting a shorter batch once linger
+// has passed since its first item (or when the input ends), so a trickle of
+// items is not held back indefinitely. A non-positive linger waits for full
+// batches.
+func Batch[T any](ctx context.Context, in <-chan T, size int, linger time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var (
+			batch []T
+			timer *time.Timer
+			timeC <-chan time.Time
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeC = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeC:
+				timer, timeC = nil, nil
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && linger > 0 {
+					timer = time.NewTimer(linger)
+					timeC = timer.C
+				}
+				if len(batch) >= size && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Window groups items into consecutive, non-overlapping windows of duration d,
+// emitting each window's items when it closes; windows without items are
+// skipped. A non-positive d makes one window that closes when the input ends.
+func Window[T any](ctx context.Context, in <-chan T, d time.Duration) <-chan []T {
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var tick <-chan time.Time
+		if d > 0 {
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		var window []T
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+				if len(window) > 0 {
+					if !send(ctx, out, window) {
+						return
+					}
+					window = nil
+				}
+			case v, ok := <-in:
+				if !ok {
+					if len(window) > 0 {
+						send(ctx, out, window)
+					}
+					return
+				}
+				window = append(window, v)
+			}
+		}
+	}()
+	return out
+}
+
+// Take passes the first n items and then closes. It stops reading in after
+// that, so cancel ctx (or its producer's) to stop an upstream that would
+// otherwise block.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			v, ok := recv(ctx, in)
+			if !ok || !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Distinct passes only the first item for each key. It remembers every key it
+// has seen, so the number of distinct keys must fit in memory.
+func Distinct[T any, K comparable](ctx context.Context, in <-chan T, key func(T) K) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		seen := map[K]struct{}{}
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			k := key(v)
+			if _, dup := seen[k]; dup {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle passes at most n items per period, letting bursts of up to n
+// through at once (a token bucket), e.g. to respect an API's rate limit before
+// Process.
+func Throttle[T any](ctx context.Context, in <-chan T, n int, per time.Duration) <-chan T {
+	n = max(n, 1)
+	interval := per / time.Duration(n)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		tokens := float64(n)
+		last := time.Now()
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			now := time.Now()
+			if interval > 0 {
+				tokens = min(float64(n), tokens+float64(now.Sub(last))/float64(interval))
+			}
+			last = now
+			if tokens < 1 {
+				wait := time.Duration((1 - tokens) * float64(interval))
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				tokens, last = 1, time.Now()
+			}
+			tokens--
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
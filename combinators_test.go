@@ -0,0 +1,134 @@
+package gojob_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestMapFilterFlatMap(t *testing.T) {
+	ctx := context.Background()
+	words := gojob.FlatMap(ctx, gojob.From(ctx, "a b", "", "c d e"), strings.Fields)
+	upper := gojob.Map(ctx, words, strings.ToUpper)
+	got := drain(gojob.Filter(ctx, upper, func(s string) bool { return s != "C" }))
+	if want := []string{"A", "B", "D", "E"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	ctx := context.Background()
+	results := gojob.Process(ctx, gojob.From(ctx, 1, 2, 3, 4), func(ctx context.Context, n int) (int, error) { return n * n, nil })
+	big := drain(gojob.Filter(ctx, results, func(r gojob.Result[int]) bool { return r.Value > 4 }))
+	if len(big) != 2 {
+		t.Errorf("got %v", big)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	got := drain(gojob.Merge(ctx, gojob.From(ctx, 1, 2), gojob.From(ctx, 3), gojob.From[int](ctx)))
+	sort.Ints(got)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	got := drain(gojob.Batch(ctx, gojob.From(ctx, 1, 2, 3, 4, 5), 2, time.Minute))
+	if want := [][]int{{1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	in := make(chan int)
+	batches := gojob.Batch(ctx, in, 100, 10*time.Millisecond)
+	in <- 1
+	select {
+	case b := <-batches:
+		if !reflect.DeepEqual(b, []int{1}) {
+			t.Errorf("got %v", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a partial batch was never emitted")
+	}
+	close(in)
+}
+
+func TestWindow(t *testing.T) {
+	ctx := context.Background()
+	in := make(chan int)
+	windows := gojob.Window(ctx, in, 50*time.Millisecond)
+	in <- 1
+	in <- 2
+	first := <-windows
+	in <- 3
+	close(in)
+	rest := drain(windows)
+	if !reflect.DeepEqual(first, []int{1, 2}) || !reflect.DeepEqual(rest, [][]int{{3}}) {
+		t.Errorf("got %v then %v", first, rest)
+	}
+
+	src, _ := gojob.Range(ctx, 0, 3, 1)
+	if got := drain(gojob.Window(ctx, src, 0)); !reflect.DeepEqual(got, [][]int{{0, 1, 2}}) {
+		t.Errorf("a zero window got %v, want one window of everything", got)
+	}
+}
+
+func TestTakeDistinct(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nums, _ := gojob.Range(ctx, 0, 1_000_000, 1)
+	mod := gojob.Map(ctx, nums, func(n int) int { return n % 3 })
+	got := drain(gojob.Take(ctx, gojob.Distinct(ctx, mod, func(n int) int { return n }), 3))
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	src, _ := gojob.Range(ctx, 0, 6, 1)
+	start := time.Now()
+	got := drain(gojob.Throttle(ctx, src, 2, 100*time.Millisecond))
+	// A burst of 2, then one item per 50ms.
+	if elapsed := time.Since(start); len(got) != 6 || elapsed < 150*time.Millisecond {
+		t.Errorf("got %d items in %v, want 6 in at least 150ms", len(got), elapsed)
+	}
+}
+
+func TestCombinatorsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int)
+	outs := []<-chan int{
+		gojob.Map(ctx, never, func(n int) int { return n }),
+		gojob.Filter(ctx, never, func(int) bool { return true }),
+		gojob.Merge(ctx, never, never),
+		gojob.Take(ctx, never, 1),
+		gojob.Throttle(ctx, never, 1, time.Second),
+	}
+	batches := []<-chan []int{gojob.Batch(ctx, never, 2, 0), gojob.Window(ctx, never, time.Hour), gojob.Window(ctx, never, -1)}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for _, o := range outs {
+			for range o {
+			}
+		}
+		for _, b := range batches {
+			for range b {
+			}
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("combinators did not close after cancellation")
+	}
+}
@@ -0,0 +1,241 @@
+package gojob
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type csvConfig struct {
+	header  bool
+	comma   rune
+	columns []string
+}
+
+// CSVOption configures ReadCSV and the CSV and TSV encoders.
+type CSVOption func(*csvConfig)
+
+// WithHeader treats the first record as a header row: columns are matched to
+// struct fields by name instead of by position, and columns with no matching
+// field are ignored. The CSV and TSV encoders write a header row.
+func WithHeader() CSVOption {
+	return func(c *csvConfig) {
+		c.header = true
+	}
+}
+
+// WithComma sets the field delimiter (default ','), e.g. '\t' for TSV.
+func WithComma(r rune) CSVOption {
+	return func(c *csvConfig) {
+		c.comma = r
+	}
+}
+
+// ReadCSV streams the records of the CSV file at path, decoding each into a T,
+// which must be a struct. path is interpreted as by Lines. A field's column name
+// is taken from its `csv:"name"` tag, or else the field name; `csv:"-"` skips
+// the field. Without WithHeader, columns map to the (non-skipped) exported
+// fields in declaration order.
+//
+// Supported field types are strings, booleans, integers, floats,
+// time.Duration, and anything implementing encoding.TextUnmarshaler; empty
+// cells leave the field at its zero value. A record that does not parse or
+// convert is skipped and reported as a *SourceError carrying its line number
+// (see WithErrors); the stream continues with the next record. With
+// WithHeader, a header row that does not parse ends the stream.
+func ReadCSV[T any](ctx context.Context, path string, opts ...CSVOption) <-chan T {
+	cfg := csvConfig{comma: ','}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Err: err})
+			return
+		}
+		f, err := openSource(path)
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Err: err})
+			return
+		}
+		defer f.Close()
+		r := csv.NewReader(f)
+		r.Comma = cfg.comma
+
+		columns, header := fields, cfg.header
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				reportErr(ctx, &SourceError{Path: path, Line: int64(pe.StartLine), Err: pe.Err})
+				if header {
+					// Without the header, columns cannot be matched by name.
+					return
+				}
+				continue
+			}
+			if err != nil {
+				reportErr(ctx, &SourceError{Path: path, Err: err})
+				return
+			}
+			line, _ := r.FieldPos(0)
+			if header {
+				columns, header = headerColumns(record, fields), false
+				continue
+			}
+			var v T
+			if err := decodeCSV(reflect.ValueOf(&v).Elem(), record, columns); err != nil {
+				reportErr(ctx, &SourceError{Path: path, Line: int64(line), Err: err})
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// csvField is a struct field addressable by a CSV column.
+type csvField struct {
+	name  string
+	index []int
+}
+
+// csvFields lists the exported fields of struct type t that map to columns.
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("CSV records decode into structs, not %s", t)
+	}
+	var fields []csvField
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("csv"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, csvField{name: name, index: sf.Index})
+	}
+	return fields, nil
+}
+
+// headerColumns maps each header column to the field of the same name; a zero
+// csvField (nil index) marks a column that is ignored.
+func headerColumns(header []string, fields []csvField) []csvField {
+	columns := make([]csvField, len(header))
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		for _, f := range fields {
+			if f.name == h {
+				columns[i] = f
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func decodeCSV(v reflect.Value, record []string, columns []csvField) error {
+	for i, cell := range record {
+		if i >= len(columns) || columns[i].index == nil || cell == "" {
+			continue
+		}
+		f, err := fieldByIndex(v, columns[i].index)
+		if err != nil {
+			return err
+		}
+		if err := setField(f, cell); err != nil {
+			return fmt.Errorf("column %q: %w", columns[i].name, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the nested field of v at index, allocating nil embedded
+// struct pointers on the way as encoding/json does.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField parses s into the field f according to its type.
+func setField(f reflect.Value, s string) error {
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
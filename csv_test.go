@@ -0,0 +1,95 @@
+package gojob_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+type host struct {
+	Name    string        `csv:"name"`
+	Port    int           `csv:"port"`
+	Timeout time.Duration `csv:"timeout"`
+	Skip    string        `csv:"-"`
+}
+
+func TestReadCSVHeader(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "hosts.csv", "port,extra,name,timeout\n80,x,a,1s\nnope,x,b,\n443,x,c,\n")
+	var got []host
+	for h := range gojob.ReadCSV[host](ctx, path, gojob.WithHeader()) {
+		got = append(got, h)
+	}
+	if len(got) != 2 || got[0] != (host{Name: "a", Port: 80, Timeout: time.Second}) || got[1] != (host{Name: "c", Port: 443}) {
+		t.Errorf("unexpected records: %+v", got)
+	}
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Line != 3 {
+		t.Errorf("expected a SourceError on line 3, got %v", errs.Err())
+	}
+}
+
+func TestReadCSVPositional(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "hosts.tsv", "a\t80\nb\t81\n")
+	var got []host
+	for h := range gojob.ReadCSV[host](ctx, path, gojob.WithComma('\t')) {
+		got = append(got, h)
+	}
+	if len(got) != 2 || got[1].Name != "b" || got[1].Port != 81 {
+		t.Errorf("unexpected records: %+v", got)
+	}
+	if err := errs.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReadCSVNotStruct(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "ints.csv", "1\n")
+	for range gojob.ReadCSV[int](ctx, path) {
+		t.Error("expected no records")
+	}
+	if errs.Len() != 1 {
+		t.Errorf("expected 1 error, got %d", errs.Len())
+	}
+}
+
+// Endpoint is embedded by pointer in record.
+type Endpoint struct {
+	Port int `csv:"port"`
+}
+
+func TestReadCSVEmbeddedPointer(t *testing.T) {
+	type record struct {
+		*Endpoint
+		Name string `csv:"name"`
+	}
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "hosts.csv", "name,port\na,80\nb,\n")
+	var got []record
+	for r := range gojob.ReadCSV[record](ctx, path, gojob.WithHeader()) {
+		got = append(got, r)
+	}
+	if len(got) != 2 || got[0].Endpoint == nil || got[0].Port != 80 || got[1].Name != "b" {
+		t.Errorf("unexpected records: %+v", got)
+	}
+	if err := errs.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReadCSVBadHeader(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "hosts.csv", "port,na\"me\n80,a\n")
+	for h := range gojob.ReadCSV[host](ctx, path, gojob.WithHeader()) {
+		t.Errorf("expected no records, got %+v", h)
+	}
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Line != 1 || errs.Len() != 1 {
+		t.Errorf("expected one SourceError on line 1, got %v", errs.Err())
+	}
+}
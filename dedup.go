@@ -0,0 +1,521 @@
+package gojob
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"hash/maphash"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type dedupConfig struct {
+	open    func() (keySet, error)
+	path    string // of the disk set, for errors
+	dropped *atomic.Int64
+	done    *DedupDone
+}
+
+// DedupOption configures Dedup.
+type DedupOption func(*dedupConfig)
+
+// WithExactSet remembers every key in memory, so no item is ever dropped by
+// mistake; this is the default, for inputs whose distinct keys fit in memory.
+func WithExactSet() DedupOption {
+	return func(c *dedupConfig) {
+		c.open = func() (keySet, error) { return exactSet{}, nil }
+		c.path = ""
+	}
+}
+
+// WithBloom remembers keys in a Bloom filter sized for expected distinct keys
+// at false-positive rate fpRate (e.g. 0.001), for inputs too large to hold:
+// a billion keys at 0.1% take about 1.8 GB. In exchange, a fraction fpRate of
+// the distinct items is dropped as if it were a duplicate.
+func WithBloom(expected int64, fpRate float64) DedupOption {
+	return func(c *dedupConfig) {
+		c.open = func() (keySet, error) { return newBloomSet(expected, fpRate), nil }
+		c.path = ""
+	}
+}
+
+// WithDiskSet remembers keys in a hash table in the file at path, creating it
+// if needed, so memory stays flat and the keys persist across restarts: a
+// rerun over the same input skips the items the previous run handed
+// downstream. Items still being worked on when that run stopped count as
+// handed down; use WithMarkDone to have a rerun skip only the items that were
+// finished. Keys are stored as 128-bit hashes, taking 32 to 64 bytes per key
+// on disk.
+func WithDiskSet(path string) DedupOption {
+	return func(c *dedupConfig) {
+		c.open = func() (keySet, error) { return openDiskSet(path) }
+		c.path = path
+	}
+}
+
+// WithDedupCounter adds every dropped duplicate to n, e.g. to pass to
+// WithDuplicates.
+func WithDedupCounter(n *atomic.Int64) DedupOption {
+	return func(c *dedupConfig) {
+		c.dropped = n
+	}
+}
+
+// WithMarkDone records a passed key in the set only once done.Mark is called
+// with it, typically after its item was processed successfully, so a rerun
+// over a WithDiskSet retries whatever an interrupted run left unfinished:
+//
+//	var done gojob.DedupDone
+//	defer done.Close()
+//	urls = gojob.Dedup(ctx, urls, func(u string) string { return u },
+//		gojob.WithDiskSet("seen.set"), gojob.WithMarkDone(&done))
+//	for r := range gojob.ProcessInputs(ctx, urls, fetch) {
+//		if r.Err == nil {
+//			done.Mark(r.Value.Input)
+//		}
+//	}
+//
+// Repeats of a passed key are still dropped within the run; the keys passed
+// but not yet marked are held in memory until they are.
+func WithMarkDone(done *DedupDone) DedupOption {
+	return func(c *dedupConfig) {
+		c.done = done
+	}
+}
+
+// Dedup passes only the first item for each key, dropping repeats before they
+// cost any work. Unlike Distinct, it can bound its memory (WithBloom) or keep
+// its keys on disk (WithDiskSet):
+//
+//	var dups atomic.Int64
+//	urls = gojob.Dedup(ctx, urls, func(u string) string { return u },
+//		gojob.WithBloom(1e9, 0.001), gojob.WithDedupCounter(&dups))
+//	...
+//	results, stats := gojob.WithStats(ctx, results, gojob.WithDuplicates(&dups))
+//
+// If the disk set cannot be opened or written, the failure is reported as a
+// *SourceError to the Errors in ctx (see WithErrors) and the output closes.
+func Dedup[T any](ctx context.Context, in <-chan T, key func(T) string, opts ...DedupOption) <-chan T {
+	cfg := dedupConfig{open: func() (keySet, error) { return exactSet{}, nil }}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		set, err := cfg.open()
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: cfg.path, Err: err})
+			return
+		}
+		if cfg.done != nil {
+			set = cfg.done.attach(set)
+		}
+		defer func() {
+			if err := set.close(); err != nil {
+				reportErr(ctx, &SourceError{Path: cfg.path, Err: err})
+			}
+		}()
+		for {
+			v, ok := recv(ctx, in)
+			if !ok {
+				return
+			}
+			k := key(v)
+			seen, err := set.has(k)
+			if err != nil {
+				reportErr(ctx, &SourceError{Path: cfg.path, Err: err})
+				return
+			}
+			if seen {
+				if cfg.dropped != nil {
+					cfg.dropped.Add(1)
+				}
+				continue
+			}
+			// The key is recorded only once the item is handed over, so one
+			// lost to cancellation is not skipped by a rerun.
+			if !send(ctx, out, v) {
+				return
+			}
+			if err := set.add(k); err != nil {
+				reportErr(ctx, &SourceError{Path: cfg.path, Err: err})
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// keySet is the memory of a Dedup.
+type keySet interface {
+	// has reports whether key was recorded.
+	has(key string) (bool, error)
+	// add records key.
+	add(key string) error
+	close() error
+}
+
+// DedupDone marks the keys a Dedup passed as done (see WithMarkDone). The zero
+// value is ready to use, for one Dedup. It is safe for concurrent use.
+type DedupDone struct {
+	mu      sync.Mutex
+	set     keySet
+	pending map[string]struct{}
+	refs    int // the Dedup and the caller, until each lets go
+	closed  bool
+}
+
+// Mark records key as done, so Dedup and later runs over the same set skip it.
+func (d *DedupDone) Mark(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.set == nil || d.closed {
+		return errors.New("gojob: DedupDone is not open")
+	}
+	delete(d.pending, key)
+	return d.set.add(key)
+}
+
+// Close releases the set once the Dedup has finished too; call it after the
+// last Mark.
+func (d *DedupDone) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.release()
+}
+
+// attach makes d the owner of set, returning the view of it the Dedup uses:
+// one that holds passed keys as pending rather than recording them.
+func (d *DedupDone) attach(set keySet) keySet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.set, d.pending, d.refs = set, map[string]struct{}{}, 1
+	if !d.closed {
+		d.refs++
+	}
+	return pendingSet{d}
+}
+
+// release drops a reference to the set, closing it with the last one.
+func (d *DedupDone) release() error {
+	if d.refs == 0 {
+		return nil
+	}
+	if d.refs--; d.refs > 0 {
+		return nil
+	}
+	return d.set.close()
+}
+
+// pendingSet is the Dedup's side of a DedupDone.
+type pendingSet struct{ d *DedupDone }
+
+func (p pendingSet) has(key string) (bool, error) {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	if _, ok := p.d.pending[key]; ok {
+		return true, nil
+	}
+	return p.d.set.has(key)
+}
+
+func (p pendingSet) add(key string) error {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	p.d.pending[key] = struct{}{}
+	return nil
+}
+
+func (p pendingSet) close() error {
+	p.d.mu.Lock()
+	defer p.d.mu.Unlock()
+	return p.d.release()
+}
+
+type exactSet map[string]struct{}
+
+func (s exactSet) has(key string) (bool, error) {
+	_, ok := s[key]
+	return ok, nil
+}
+
+func (s exactSet) add(key string) error {
+	s[key] = struct{}{}
+	return nil
+}
+
+func (exactSet) close() error { return nil }
+
+// bloomSet is a Bloom filter; its k bit positions come from two independent
+// hashes (Kirsch and Mitzenmacher's double hashing).
+type bloomSet struct {
+	bits   []uint64
+	m      uint64
+	k      int
+	s1, s2 maphash.Seed
+}
+
+func newBloomSet(expected int64, fpRate float64) *bloomSet {
+	if expected <= 0 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m := uint64(math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := max(int(math.Round(float64(m)/float64(expected)*math.Ln2)), 1)
+	return &bloomSet{bits: make([]uint64, (m+63)/64), m: m, k: k, s1: maphash.MakeSeed(), s2: maphash.MakeSeed()}
+}
+
+func (b *bloomSet) has(key string) (bool, error) {
+	h1, h2 := maphash.String(b.s1, key), maphash.String(b.s2, key)|1
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *bloomSet) add(key string) error {
+	h1, h2 := maphash.String(b.s1, key), maphash.String(b.s2, key)|1
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+	return nil
+}
+
+func (*bloomSet) close() error { return nil }
+
+// diskSet is an open-addressing hash table of 128-bit key hashes in a file: a
+// header, then slots of 16 bytes, zero when empty. It doubles, by rewriting
+// the file, when half full.
+type diskSet struct {
+	path  string
+	f     *os.File
+	slots uint64
+	n     uint64
+	// hint is where has last found a missing key would go, sparing add,
+	// which normally follows it, a second probe.
+	hint struct {
+		fp  [diskSetSlot]byte
+		off int64
+		ok  bool
+	}
+}
+
+const (
+	diskSetMagic    = "gojobset"
+	diskSetHeader   = 16 // magic, slot count
+	diskSetSlot     = 16
+	diskSetMinSlots = 1 << 16
+)
+
+func openDiskSet(path string) (*diskSet, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &diskSet{path: path, f: f}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the header of an existing table and counts its keys, or
+// initializes an empty file.
+func (s *diskSet) load() error {
+	var header [diskSetHeader]byte
+	_, err := s.f.ReadAt(header[:], 0)
+	if errors.Is(err, io.EOF) {
+		return s.init(s.f, diskSetMinSlots)
+	}
+	if err != nil {
+		return err
+	}
+	if string(header[:8]) != diskSetMagic {
+		return fmt.Errorf("gojob: %s is not a dedup set", s.path)
+	}
+	s.slots = binary.LittleEndian.Uint64(header[8:])
+	buf := make([]byte, 64<<10)
+	r := io.NewSectionReader(s.f, diskSetHeader, int64(s.slots*diskSetSlot))
+	for {
+		n, err := io.ReadFull(r, buf)
+		for i := 0; i+diskSetSlot <= n; i += diskSetSlot {
+			if !isZero(buf[i : i+diskSetSlot]) {
+				s.n++
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// init writes the header of an empty table of the given size to f.
+func (s *diskSet) init(f *os.File, slots uint64) error {
+	var header [diskSetHeader]byte
+	copy(header[:], diskSetMagic)
+	binary.LittleEndian.PutUint64(header[8:], slots)
+	if _, err := f.WriteAt(header[:], 0); err != nil {
+		return err
+	}
+	s.slots = slots
+	return f.Truncate(int64(diskSetHeader + slots*diskSetSlot))
+}
+
+func (s *diskSet) has(key string) (bool, error) {
+	fp := fingerprint(key)
+	off, found, err := s.probe(s.f, fp)
+	if err != nil || found {
+		return found, err
+	}
+	s.hint.fp, s.hint.off, s.hint.ok = fp, off, true
+	return false, nil
+}
+
+func (s *diskSet) add(key string) error {
+	fp := fingerprint(key)
+	off := s.hint.off
+	if !s.hint.ok || s.hint.fp != fp {
+		var found bool
+		var err error
+		if off, found, err = s.probe(s.f, fp); err != nil || found {
+			return err
+		}
+	}
+	s.hint.ok = false
+	if _, err := s.f.WriteAt(fp[:], off); err != nil {
+		return err
+	}
+	s.n++
+	if s.n*2 > s.slots {
+		return s.grow()
+	}
+	return nil
+}
+
+// fingerprint is the 128-bit hash of key stored in the table.
+func fingerprint(key string) [diskSetSlot]byte {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	var fp [diskSetSlot]byte
+	h.Sum(fp[:0])
+	if isZero(fp[:]) {
+		fp[0] = 1 // zero marks an empty slot
+	}
+	return fp
+}
+
+// probe looks fp up in the table in f, returning the offset of its slot, or of
+// the empty slot it would go in.
+func (s *diskSet) probe(f *os.File, fp [diskSetSlot]byte) (int64, bool, error) {
+	var slot [diskSetSlot]byte
+	for i := slotOf(fp) % s.slots; ; i = (i + 1) % s.slots {
+		off := int64(diskSetHeader + i*diskSetSlot)
+		if _, err := f.ReadAt(slot[:], off); err != nil {
+			return 0, false, err
+		}
+		if slot == fp {
+			return off, true, nil
+		}
+		if isZero(slot[:]) {
+			return off, false, nil
+		}
+	}
+}
+
+// grow rehashes the table into a file twice the size and swaps it in.
+func (s *diskSet) grow() error {
+	tmp, err := os.Create(s.path + ".tmp")
+	if err != nil {
+		return err
+	}
+	oldSlots := s.slots
+	if err := s.rehash(tmp, oldSlots); err != nil {
+		tmp.Close()
+		s.slots = oldSlots
+		return err
+	}
+	if err := os.Rename(s.path+".tmp", s.path); err != nil {
+		tmp.Close()
+		s.slots = oldSlots
+		return err
+	}
+	old := s.f
+	s.f = tmp
+	return old.Close()
+}
+
+// rehash copies the keys of the current table, of oldSlots slots, into an
+// empty table of twice the size in f.
+func (s *diskSet) rehash(f *os.File, oldSlots uint64) error {
+	if err := s.init(f, oldSlots*2); err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(s.f, diskSetHeader, int64(oldSlots*diskSetSlot)), 64<<10)
+	var fp [diskSetSlot]byte
+	for {
+		if _, err := io.ReadFull(r, fp[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if isZero(fp[:]) {
+			continue
+		}
+		off, found, err := s.probe(f, fp)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if _, err := f.WriteAt(fp[:], off); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *diskSet) close() error {
+	return s.f.Close()
+}
+
+// slotOf mixes both halves of fp into a table position; FNV alone spreads
+// short keys poorly over its high bits, which would cluster the probes.
+func slotOf(fp [diskSetSlot]byte) uint64 {
+	x := binary.LittleEndian.Uint64(fp[:8]) ^ binary.LittleEndian.Uint64(fp[8:])
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	return x ^ x>>33
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
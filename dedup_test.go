@@ -0,0 +1,150 @@
+package gojob_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestDedupModes(t *testing.T) {
+	ctx := context.Background()
+	words := strings.Fields("a b a c b a d")
+	for name, opt := range map[string]gojob.DedupOption{
+		"exact": gojob.WithExactSet(),
+		"bloom": gojob.WithBloom(100, 0.001),
+		"disk":  gojob.WithDiskSet(filepath.Join(t.TempDir(), "seen.set")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var dups atomic.Int64
+			got := drain(gojob.Dedup(ctx, gojob.From(ctx, words...), func(s string) string { return s }, opt, gojob.WithDedupCounter(&dups)))
+			if strings.Join(got, " ") != "a b c d" {
+				t.Errorf("got %v, want [a b c d]", got)
+			}
+			if dups.Load() != 3 {
+				t.Errorf("dropped %d duplicates, want 3", dups.Load())
+			}
+		})
+	}
+}
+
+func TestDedupDiskSetPersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "seen.set")
+	key := func(s string) string { return s }
+	// Enough keys to make the table grow twice.
+	var first []string
+	for i := 0; i < 100_000; i++ {
+		first = append(first, fmt.Sprint(i))
+	}
+	if got := drain(gojob.Dedup(ctx, gojob.From(ctx, first...), key, gojob.WithDiskSet(path))); len(got) != len(first) {
+		t.Fatalf("first run passed %d of %d distinct keys", len(got), len(first))
+	}
+
+	// A rerun skips what the first one passed.
+	got := drain(gojob.Dedup(ctx, gojob.From(ctx, "5", "new", "99999", "new"), key, gojob.WithDiskSet(path)))
+	if strings.Join(got, " ") != "new" {
+		t.Errorf("second run got %v, want [new]", got)
+	}
+}
+
+func TestDedupDiskSetCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.set")
+	key := func(s string) string { return s }
+	ctx, cancel := context.WithCancel(context.Background())
+	out := gojob.Dedup(ctx, gojob.From(ctx, "a", "b", "c"), key, gojob.WithDiskSet(path))
+	if v := <-out; v != "a" {
+		t.Fatalf("got %q, want a", v)
+	}
+	// Dedup is on its way to handing over "b"; whatever it had not handed
+	// over when cancelled must not count as passed.
+	cancel()
+	passed := map[string]bool{"a": true}
+	for _, v := range drain(out) {
+		passed[v] = true
+	}
+	var want []string
+	for _, v := range []string{"a", "b", "c"} {
+		if !passed[v] {
+			want = append(want, v)
+		}
+	}
+
+	ctx = context.Background()
+	got := drain(gojob.Dedup(ctx, gojob.From(ctx, "a", "b", "c"), key, gojob.WithDiskSet(path)))
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("rerun got %v, want %v", got, want)
+	}
+}
+
+func TestDedupMarkDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.set")
+	key := func(s string) string { return s }
+	ctx := context.Background()
+	var done gojob.DedupDone
+	got := drain(gojob.Dedup(ctx, gojob.From(ctx, "a", "b", "a", "c", "b"), key, gojob.WithDiskSet(path), gojob.WithMarkDone(&done)))
+	if strings.Join(got, " ") != "a b c" {
+		t.Errorf("got %v, want [a b c]", got)
+	}
+	// "b" failed, so only the others are marked.
+	for _, k := range []string{"a", "c"} {
+		if err := done.Mark(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := done.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := done.Mark("b"); err == nil {
+		t.Error("Mark after Close should fail")
+	}
+
+	got = drain(gojob.Dedup(ctx, gojob.From(ctx, "a", "b", "c", "b"), key, gojob.WithDiskSet(path)))
+	if strings.Join(got, " ") != "b" {
+		t.Errorf("rerun got %v, want [b]", got)
+	}
+}
+
+func TestDedupDiskSetError(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := filepath.Join(t.TempDir(), "not-a-set")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 64), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := drain(gojob.Dedup(ctx, gojob.From(ctx, "a"), func(s string) string { return s }, gojob.WithDiskSet(path)))
+	if len(got) != 0 || errs.Len() != 1 {
+		t.Errorf("got %v and %d errors, want nothing and 1 error", got, errs.Len())
+	}
+}
+
+func TestBloomFalsePositiveRate(t *testing.T) {
+	ctx := context.Background()
+	const n = 100_000
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	got := drain(gojob.Dedup(ctx, gojob.From(ctx, keys...), func(i int) string { return fmt.Sprint(i) }, gojob.WithBloom(n, 0.01)))
+	if lost := n - len(got); lost > n*2/100 {
+		t.Errorf("dropped %d of %d distinct keys, want about 1%%", lost, n)
+	}
+}
+
+func TestStatsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	var dups atomic.Int64
+	src := gojob.Dedup(ctx, gojob.From(ctx, 1, 1, 2), func(i int) string { return fmt.Sprint(i) }, gojob.WithDedupCounter(&dups))
+	results, stats := gojob.WithStats(ctx, gojob.Process(ctx, src, func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}), gojob.WithDuplicates(&dups))
+	gojob.Drain(results)
+	if snap := stats.Snapshot(); snap.Done != 2 || snap.Duplicates != 1 {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+}
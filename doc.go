@@ -10,6 +10,7 @@
 //
 //	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 //	defer cancel()
+//	ctx, _ = gojob.WithErrors(ctx) // source failures fail the sink
 //
 //	urls := gojob.Lines(ctx, "input.txt") // <-chan string
 //	urls = gojob.Shard(ctx, urls, 4, 0)   // keep this shard's slice
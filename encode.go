@@ -0,0 +1,359 @@
+package gojob
+
+import (
+	"bufio"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Encoder renders results in an output format for Write. Write calls Encode
+// for every result and Flush once, when the stream ends or the write stops
+// early. Encoders should pass each result on to w rather than hold it back, so
+// that Write's durability options (see WithFlushEvery) cover it; formats that
+// cannot, such as columnar files, only make their output durable on Flush. An
+// Encoder holds per-output state (such as whether the header row was
+// written), so use a fresh one per Write.
+type Encoder[T any] interface {
+	Encode(w io.Writer, r Result[T]) error
+	Flush(w io.Writer) error
+}
+
+// Write encodes each result to w with enc until the stream ends or ctx is
+// cancelled, returning the first encode error (or the ctx error). Like
+// WriteJSONL, it returns the source errors collected in ctx (see WithErrors)
+// once the stream ends. opts control buffering and durability, as for
+// WriteJSONL.
+//
+//	err := gojob.Write(ctx, os.Stdout, results, gojob.CSV[Page](gojob.WithHeader()))
+func Write[T any](ctx context.Context, w io.Writer, in <-chan Result[T], enc Encoder[T], opts ...WriteOption) error {
+	var cfg writeConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	f := newFlusher(w, cfg, enc)
+	var tick <-chan time.Time
+	if cfg.interval > 0 {
+		t := time.NewTicker(cfg.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if err := f.close(enc); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-tick:
+			if err := f.flush(); err != nil {
+				return err
+			}
+		case r, ok := <-in:
+			if !ok {
+				if err := f.close(enc); err != nil {
+					return err
+				}
+				return sourceErr(ctx)
+			}
+			if err := enc.Encode(f.w, r); err != nil {
+				return err
+			}
+			f.pending++
+			if cfg.every > 0 && f.pending >= cfg.every {
+				if err := f.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// flusher is the buffering and syncing layer between an Encoder and the
+// writer given to Write.
+type flusher struct {
+	w       io.Writer // what the encoder writes to
+	enc     any
+	buf     *bufio.Writer
+	sync    interface{ Sync() error } // nil unless syncing
+	pending int64                     // results encoded since the last flush
+}
+
+func newFlusher(w io.Writer, cfg writeConfig, enc any) *flusher {
+	f := &flusher{w: w, enc: enc}
+	if cfg.bufSize > 0 {
+		f.buf = bufio.NewWriterSize(w, cfg.bufSize)
+		f.w = f.buf
+	}
+	if cfg.sync {
+		f.sync, _ = w.(interface{ Sync() error })
+	}
+	return f
+}
+
+// flush pushes buffered output, including any the encoder holds (see
+// bufferingEncoder), to the underlying writer and, with WithSync, to stable
+// storage.
+func (f *flusher) flush() error {
+	if f.pending == 0 {
+		return nil
+	}
+	f.pending = 0
+	if enc, ok := f.enc.(bufferingEncoder); ok {
+		if err := enc.flush(); err != nil {
+			return err
+		}
+	}
+	if f.buf != nil {
+		if err := f.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	if f.sync != nil {
+		return f.sync.Sync()
+	}
+	return nil
+}
+
+// bufferingEncoder is an Encoder that buffers the results it encodes; flush
+// passes them on to the writer given to Encode. Write calls it whenever it
+// flushes, so the encoder need not write through on every result.
+type bufferingEncoder interface {
+	flush() error
+}
+
+// close completes the encoder's output and flushes it.
+func (f *flusher) close(enc interface{ Flush(io.Writer) error }) error {
+	if err := enc.Flush(f.w); err != nil {
+		return err
+	}
+	f.pending++ // the encoder may have written a trailer
+	return f.flush()
+}
+
+// JSONL returns the Encoder WriteJSONL uses: one JSON object per line, in the
+// shape of Result.MarshalJSON.
+func JSONL[T any]() Encoder[T] { return jsonlEncoder[T]{} }
+
+type jsonlEncoder[T any] struct{}
+
+func (jsonlEncoder[T]) Encode(w io.Writer, r Result[T]) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+func (jsonlEncoder[T]) Flush(io.Writer) error { return nil }
+
+// CSV returns an Encoder that writes one CSV record per result, for outputs
+// that open directly in a spreadsheet. A struct Value (or pointer to one) is
+// flattened into a column per field, named and skipped with `csv` tags as in
+// ReadCSV; any other Value becomes a single "value" column. They are followed
+// by the "error", "attempts", "started_at" (RFC 3339) and "duration_ms"
+// columns.
+//
+// WithHeader writes a header row first, WithColumns selects and orders the
+// columns, and WithComma changes the delimiter. Cells are formatted as ReadCSV
+// parses them: time.Duration as "1.5s", encoding.TextMarshaler as its text;
+// values of other types (slices, maps, nested structs) are written as JSON.
+func CSV[T any](opts ...CSVOption) Encoder[T] {
+	cfg := csvConfig{comma: ','}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &csvEncoder[T]{cfg: cfg}
+}
+
+// TSV is CSV with tab-separated fields.
+func TSV[T any](opts ...CSVOption) Encoder[T] {
+	return CSV[T](append([]CSVOption{WithComma('\t')}, opts...)...)
+}
+
+// WithColumns selects the columns an encoder from CSV or TSV writes, in the
+// given order, by name. It has no effect on ReadCSV.
+func WithColumns(names ...string) CSVOption {
+	return func(c *csvConfig) {
+		c.columns = append(c.columns, names...)
+	}
+}
+
+type csvEncoder[T any] struct {
+	cfg     csvConfig
+	w       *csv.Writer
+	columns []csvColumn[T]
+	err     error
+}
+
+// csvColumn renders one cell of a result.
+type csvColumn[T any] struct {
+	name string
+	cell func(Result[T]) (string, error)
+}
+
+func (e *csvEncoder[T]) Encode(w io.Writer, r Result[T]) error {
+	if err := e.start(w); err != nil {
+		return err
+	}
+	record := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		cell, err := c.cell(r)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", c.name, err)
+		}
+		record[i] = cell
+	}
+	// The csv.Writer buffers; Write flushes it along with its own buffer.
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder[T]) Flush(w io.Writer) error {
+	if err := e.start(w); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+func (e *csvEncoder[T]) flush() error {
+	if e.w == nil {
+		return nil
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// start resolves the columns and writes the header row, once.
+func (e *csvEncoder[T]) start(w io.Writer) error {
+	if e.w != nil || e.err != nil {
+		return e.err
+	}
+	e.columns, e.err = resultColumns[T](e.cfg.columns)
+	if e.err != nil {
+		return e.err
+	}
+	e.w = csv.NewWriter(w)
+	e.w.Comma = e.cfg.comma
+	if e.cfg.header {
+		header := make([]string, len(e.columns))
+		for i, c := range e.columns {
+			header[i] = c.name
+		}
+		e.err = e.w.Write(header)
+	}
+	return e.err
+}
+
+// resultColumns lists the columns of a Result[T]: the flattened Value, then the
+// metadata. If names is non-empty, it picks those columns in that order.
+func resultColumns[T any](names []string) ([]csvColumn[T], error) {
+	var all []csvColumn[T]
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	st, ptr := t, false
+	if st.Kind() == reflect.Pointer && st.Elem().Kind() == reflect.Struct {
+		st, ptr = st.Elem(), true
+	}
+	if st.Kind() == reflect.Struct && !st.Implements(textMarshalerType) && !reflect.PointerTo(st).Implements(textMarshalerType) {
+		fields, err := csvFields(st)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			all = append(all, csvColumn[T]{name: f.name, cell: func(r Result[T]) (string, error) {
+				v := reflect.ValueOf(&r.Value).Elem()
+				if ptr {
+					if v.IsNil() {
+						return "", nil
+					}
+					v = v.Elem()
+				}
+				fv, err := v.FieldByIndexErr(f.index)
+				if err != nil {
+					return "", nil // through a nil embedded pointer
+				}
+				return formatField(fv)
+			}})
+		}
+	} else {
+		all = append(all, csvColumn[T]{name: "value", cell: func(r Result[T]) (string, error) {
+			return formatField(reflect.ValueOf(&r.Value).Elem())
+		}})
+	}
+	all = append(all,
+		csvColumn[T]{name: "error", cell: func(r Result[T]) (string, error) {
+			if r.Err == nil {
+				return "", nil
+			}
+			return r.Err.Error(), nil
+		}},
+		csvColumn[T]{name: "attempts", cell: func(r Result[T]) (string, error) {
+			return strconv.Itoa(r.Attempts), nil
+		}},
+		csvColumn[T]{name: "started_at", cell: func(r Result[T]) (string, error) {
+			if r.StartedAt.IsZero() {
+				return "", nil
+			}
+			return r.StartedAt.Format(time.RFC3339Nano), nil
+		}},
+		csvColumn[T]{name: "duration_ms", cell: func(r Result[T]) (string, error) {
+			return strconv.FormatInt(r.Duration.Milliseconds(), 10), nil
+		}},
+	)
+	if len(names) == 0 {
+		return all, nil
+	}
+	picked := make([]csvColumn[T], 0, len(names))
+next:
+	for _, name := range names {
+		for _, c := range all {
+			if c.name == name {
+				picked = append(picked, c)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("gojob: no column %q", name)
+	}
+	return picked, nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// formatField renders the field f as a cell, the inverse of setField.
+func formatField(f reflect.Value) (string, error) {
+	for f.Kind() == reflect.Interface {
+		if f.IsNil() {
+			return "", nil
+		}
+		f = f.Elem()
+	}
+	// Checked after unwrapping too: an interface may hold a nil pointer.
+	if f.Kind() == reflect.Pointer && f.IsNil() {
+		return "", nil
+	}
+	if m, ok := f.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if f.Kind() == reflect.Pointer {
+		f = f.Elem()
+	}
+	if f.Type() == durationType {
+		return time.Duration(f.Int()).String(), nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, f.Type().Bits()), nil
+	}
+	b, err := json.Marshal(f.Interface())
+	return string(b), err
+}
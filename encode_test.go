@@ -0,0 +1,114 @@
+package gojob_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+type page struct {
+	URL     string        `csv:"url"`
+	Status  int           `csv:"status"`
+	Latency time.Duration `csv:"latency"`
+	Addr    netip.Addr    `csv:"addr"`
+	Tags    []string      `csv:"tags"`
+	Secret  string        `csv:"-"`
+}
+
+func pageResults() []gojob.Result[page] {
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []gojob.Result[page]{
+		{Value: page{URL: "https://a.example", Status: 200, Latency: 1500 * time.Millisecond, Addr: netip.MustParseAddr("10.0.0.1"), Tags: []string{"x", "y"}, Secret: "s"}, Attempts: 1, StartedAt: started, Duration: 42 * time.Millisecond},
+		{Value: page{URL: "https://b.example, with comma"}, Err: errors.New("boom"), Attempts: 3},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if err := gojob.Write(ctx, &buf, gojob.From(ctx, pageResults()...), gojob.CSV[page](gojob.WithHeader())); err != nil {
+		t.Fatal(err)
+	}
+	want := "url,status,latency,addr,tags,error,attempts,started_at,duration_ms\n" +
+		`https://a.example,200,1.5s,10.0.0.1,"[""x"",""y""]",,1,2024-01-02T03:04:05Z,42` + "\n" +
+		`"https://b.example, with comma",0,0s,,null,boom,3,,0` + "\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteTSVColumns(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	enc := gojob.TSV[*page](gojob.WithHeader(), gojob.WithColumns("error", "url"))
+	results := []gojob.Result[*page]{{Value: &page{URL: "a"}}, {Err: errors.New("nil value")}}
+	if err := gojob.Write(ctx, &buf, gojob.From(ctx, results...), enc); err != nil {
+		t.Fatal(err)
+	}
+	if want := "error\turl\n\ta\nnil value\t\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCSVScalar(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	results := gojob.Process(ctx, gojob.From(ctx, 7), func(ctx context.Context, n int) (int, error) { return n * 6, nil })
+	if err := gojob.Write(ctx, &buf, results, gojob.CSV[int](gojob.WithColumns("value", "attempts"))); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "42,1\n" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestWriteCSVEmptyAndUnknownColumn(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if err := gojob.Write(ctx, &buf, gojob.From[gojob.Result[page]](ctx), gojob.CSV[page](gojob.WithHeader(), gojob.WithColumns("url"))); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "url\n" {
+		t.Errorf("empty stream should still get a header, got %q", buf.String())
+	}
+	err := gojob.Write(ctx, &buf, gojob.From(ctx, pageResults()...), gojob.CSV[page](gojob.WithColumns("nope")))
+	if err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestWriteCSVInterfaceFields(t *testing.T) {
+	type inner struct{ N int }
+	type row struct {
+		A any    `csv:"a"`
+		B any    `csv:"b"`
+		C any    `csv:"c"`
+		D *inner `csv:"d"`
+	}
+	ctx := context.Background()
+	var buf bytes.Buffer
+	in := gojob.From(ctx, gojob.Result[row]{Value: row{A: (*inner)(nil), B: &inner{N: 1}, C: 7}})
+	if err := gojob.Write(ctx, &buf, in, gojob.CSV[row](gojob.WithColumns("a", "b", "c", "d"))); err != nil {
+		t.Fatal(err)
+	}
+	if want := `,"{""N"":1}",7,` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCSVFlushEvery(t *testing.T) {
+	ctx := context.Background()
+	var w syncWriter
+	if err := gojob.Write(ctx, &w, intResults(ctx, 10), gojob.CSV[int](gojob.WithColumns("value")), gojob.WithFlushEvery(4), gojob.WithSync()); err != nil {
+		t.Fatal(err)
+	}
+	if lines, writes, syncs := w.counts(); lines != 10 || writes != 3 || syncs != 3 {
+		t.Errorf("got %d lines, %d writes, %d syncs; want 10, 3, 3", lines, writes, syncs)
+	}
+}
@@ -0,0 +1,119 @@
+package gojob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// SourceError reports a record a source could not decode, or the failure that
+// ended a source early. Line is the 1-based line number of the record, or 0
+// when the error is not tied to a line (e.g. the source could not be opened).
+type SourceError struct {
+	Path string
+	Line int64
+	Err  error
+}
+
+func (e *SourceError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("gojob: %s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("gojob: %s: %v", e.Path, e.Err)
+}
+
+func (e *SourceError) Unwrap() error { return e.Err }
+
+// maxKeptErrors bounds how many errors an Errors keeps, so a source full of
+// malformed records cannot exhaust memory. The rest are only counted.
+const maxKeptErrors = 100
+
+// Errors collects the errors reported by sources running under a context from
+// WithErrors. It is safe for concurrent use.
+type Errors struct {
+	mu   sync.Mutex
+	errs []error
+	n    int64
+}
+
+type errorsKey struct{}
+
+// WithErrors returns a copy of parent that sources report their errors to, and
+// the Errors that collects them. Without it, sources only log their errors and
+// a source that cannot be opened looks like an empty input.
+//
+// Stages running under the returned context propagate the collected errors:
+// WriteJSONL returns them once its input ends, and Stats.Err reports them, so
+// a typo in an input path fails the run instead of completing with 0 items.
+//
+//	ctx, errs := gojob.WithErrors(ctx)
+//	urls := gojob.Lines(ctx, "urls.txt")
+//	...
+//	if err := gojob.WriteJSONL(ctx, out, results); err != nil { ... } // includes errs.Err()
+func WithErrors(parent context.Context) (context.Context, *Errors) {
+	e := &Errors{}
+	return context.WithValue(parent, errorsKey{}, e), e
+}
+
+// Err returns the collected errors joined together, or nil if there were none
+// (or e is nil).
+func (e *Errors) Err() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.n == 0 {
+		return nil
+	}
+	errs := e.errs
+	if dropped := e.n - int64(len(e.errs)); dropped > 0 {
+		errs = append(errs[:len(errs):len(errs)], fmt.Errorf("gojob: and %d more source errors", dropped))
+	}
+	return errors.Join(errs...)
+}
+
+// Len returns the number of errors reported so far.
+func (e *Errors) Len() int64 {
+	if e == nil {
+		return 0
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.n
+}
+
+func (e *Errors) add(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.n++
+	if len(e.errs) < maxKeptErrors {
+		e.errs = append(e.errs, err)
+	}
+}
+
+// ErrorsFrom returns the Errors installed in ctx by WithErrors, or nil. Sinks
+// outside this package use it to fail on source errors as WriteJSONL does:
+//
+//	return gojob.ErrorsFrom(ctx).Err() // nil-safe
+func ErrorsFrom(ctx context.Context) *Errors {
+	e, _ := ctx.Value(errorsKey{}).(*Errors)
+	return e
+}
+
+// sourceErr returns the source errors collected in ctx, if any.
+func sourceErr(ctx context.Context) error {
+	return ErrorsFrom(ctx).Err()
+}
+
+// reportErr hands a source error to the Errors in ctx, or logs it if there is
+// none.
+func reportErr(ctx context.Context, err *SourceError) {
+	if e := ErrorsFrom(ctx); e != nil {
+		e.add(err)
+		return
+	}
+	slog.Error("gojob: source error", slog.String("path", err.Path), slog.Int64("line", err.Line), slog.String("error", err.Err.Error()))
+}
@@ -61,6 +61,9 @@ func main() {
 	// One context governs the whole pipeline; Ctrl-C cancels every stage.
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
+	// Source errors (an unreadable input) fail the sink instead of passing for
+	// an empty input.
+	ctx, _ = gojob.WithErrors(ctx)
 
 	out, err := uio.Open(*output)
 	if err != nil {
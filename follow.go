@@ -0,0 +1,196 @@
+package gojob
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Follow streams the lines of the local file (or FIFO) at path like tail -F:
+// it reads the existing content, then keeps polling for appended lines, and
+// never closes until ctx is cancelled. If the file is rotated (replaced by a
+// new file at path) the old one is read to its end and the new one is
+// followed from its start; if it is truncated, it is re-read from the start. A
+// file that does not exist yet is waited for.
+//
+// Records are newline separated; WithSplit and WithConcurrentFiles do not
+// apply, but the other LinesOptions do, and WithPollInterval sets the polling
+// period. A trailing partial line is held back until its newline arrives (or
+// the file is rotated); a record longer than WithMaxLineSize is skipped and
+// reported as a *SourceError (see WithErrors) as soon as it outgrows the
+// limit, without being held in memory. Since the input never ends, Stats
+// observing a followed stream has an unknown total.
+func Follow(ctx context.Context, path string, opts ...LinesOption) <-chan string {
+	cfg := linesDefaults()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		t := &tailer{path: path, cfg: cfg}
+		defer t.close()
+		for {
+			line, err := t.next(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case out <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// tailer follows one path across rotations and truncations.
+type tailer struct {
+	path    string
+	cfg     linesConfig
+	f       *os.File
+	r       *bufio.Reader
+	offset  int64
+	n       int64
+	pending []byte
+	skip    bool // discarding the rest of an over-long record
+	waiting bool // the missing-file warning has been logged
+	stop    func() bool
+}
+
+// next returns the next record to emit, waiting for one as long as needed. It
+// only fails once ctx is cancelled.
+func (t *tailer) next(ctx context.Context) (string, error) {
+	for {
+		if t.f == nil && !t.open(ctx) {
+			if err := t.wait(ctx); err != nil {
+				return "", err
+			}
+			continue
+		}
+		// ReadSlice returns at most a buffer's worth, so an over-long record
+		// is caught before it is held in full.
+		chunk, err := t.r.ReadSlice('\n')
+		t.offset += int64(len(chunk))
+		if !t.skip {
+			size := len(t.pending) + len(chunk)
+			if err == nil {
+				size-- // the newline
+			}
+			if size > t.cfg.maxSize {
+				reportErr(ctx, &SourceError{Path: t.path, Line: t.n + 1, Err: bufio.ErrTooLong})
+				t.pending, t.skip = t.pending[:0], true
+			} else {
+				t.pending = append(t.pending, chunk...)
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err == nil {
+			t.n++
+			raw, skipped := string(t.pending), t.skip
+			t.pending, t.skip = t.pending[:0], false
+			if line, ok := t.cfg.record(strings.TrimRight(raw, "\r\n")); ok && !skipped {
+				return line, nil
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if !errors.Is(err, io.EOF) {
+			reportErr(ctx, &SourceError{Path: t.path, Line: t.n + 1, Err: err})
+			t.close()
+		} else if line, ok := t.rotated(); ok {
+			if line != "" {
+				return line, nil
+			}
+			continue
+		}
+		if err := t.wait(ctx); err != nil {
+			return "", err
+		}
+	}
+}
+
+// rotated checks, at end of file, whether path now names a different file or
+// the file shrank. On rotation it returns the held-back partial line, if any,
+// and arranges for the new file to be opened; on truncation it rewinds. ok
+// reports whether either happened.
+func (t *tailer) rotated() (line string, ok bool) {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return "", false // moved away and not yet recreated; keep draining
+	}
+	cur, err := t.f.Stat()
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case !os.SameFile(fi, cur):
+		raw := string(t.pending)
+		t.close()
+		if line, ok := t.cfg.record(strings.TrimRight(raw, "\r\n")); ok && raw != "" {
+			return line, true
+		}
+		return "", true
+	case fi.Mode().IsRegular() && fi.Size() < t.offset:
+		if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+			t.close()
+			return "", true
+		}
+		t.r.Reset(t.f)
+		t.offset, t.n, t.pending, t.skip = 0, 0, t.pending[:0], false
+		return "", true
+	}
+	return "", false
+}
+
+func (t *tailer) open(ctx context.Context) bool {
+	flag := os.O_RDONLY
+	if fi, err := os.Stat(t.path); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+		// Opening a FIFO would block until a writer shows up; opened without
+		// blocking, it reads as empty until then.
+		flag |= syscall.O_NONBLOCK
+	}
+	f, err := os.OpenFile(t.path, flag, 0)
+	if err != nil {
+		if !t.waiting {
+			slog.Warn("gojob: waiting for file", slog.String("path", t.path), slog.String("error", err.Error()))
+			t.waiting = true
+		}
+		return false
+	}
+	t.f, t.r, t.waiting = f, bufio.NewReader(f), false
+	t.offset, t.n, t.pending, t.skip = 0, 0, t.pending[:0], false
+	// A read waiting on a FIFO for its writer returns on cancellation.
+	t.stop = context.AfterFunc(ctx, func() { f.SetReadDeadline(time.Now()) })
+	return true
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.stop()
+		t.f.Close()
+		t.f = nil
+	}
+}
+
+func (t *tailer) wait(ctx context.Context) error {
+	timer := time.NewTimer(t.cfg.poll)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package gojob_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestFollowFIFO(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "pipe")
+	if err := syscall.Mkfifo(path, 0o644); err != nil {
+		t.Skip(err)
+	}
+	lines := gojob.Follow(ctx, path, gojob.WithPollInterval(5*time.Millisecond))
+
+	// No writer yet: Follow must not be stuck opening the FIFO.
+	time.Sleep(20 * time.Millisecond)
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.WriteString("a\n"); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(t, lines, "a")
+
+	// With the writer idle, cancellation still ends the stream.
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		drain(lines)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow did not close after cancellation")
+	}
+}
@@ -0,0 +1,107 @@
+package gojob_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+// expectLine reads one line from lines or fails after a timeout.
+func expectLine(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-lines:
+		if got != want {
+			t.Fatalf("want %q, got %q", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "a\n")
+
+	lines := gojob.Follow(ctx, path, gojob.WithPollInterval(5*time.Millisecond), gojob.WithSkipEmpty())
+	expectLine(t, lines, "a")
+
+	// A partial line is held back until its newline arrives.
+	appendFile(t, path, "\nb")
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "c\n")
+	expectLine(t, lines, "bc")
+
+	// Rotation: the old file is drained, then the new one is followed.
+	appendFile(t, path, "d\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "e\n")
+	expectLine(t, lines, "d")
+	expectLine(t, lines, "e")
+	appendFile(t, path, "a longer line\n")
+	expectLine(t, lines, "a longer line")
+
+	// Truncation (to less than was read): the file is re-read from the start.
+	if err := os.WriteFile(path, []byte("f\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(t, lines, "f")
+
+	cancel()
+	select {
+	case _, ok := <-lines:
+		for ok {
+			_, ok = <-lines
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow did not close after cancellation")
+	}
+}
+
+func TestFollowWaitsForFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "later.log")
+	lines := gojob.Follow(ctx, path, gojob.WithPollInterval(5*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, "x\n")
+	expectLine(t, lines, "x")
+}
+
+func TestFollowLongLine(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, errs := gojob.WithErrors(ctx)
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "short\n"+strings.Repeat("x", 10_000)+"\nafter\n")
+	lines := gojob.Follow(ctx, path, gojob.WithPollInterval(5*time.Millisecond), gojob.WithMaxLineSize(8))
+	expectLine(t, lines, "short")
+	expectLine(t, lines, "after")
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Line != 2 || !errors.Is(se, bufio.ErrTooLong) {
+		t.Errorf("expected line 2 to be reported as too long, got %v", errs.Err())
+	}
+}
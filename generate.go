@@ -0,0 +1,239 @@
+package gojob
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"net/netip"
+)
+
+// The generators below produce their items lazily, so scanning an address
+// space or a parameter grid needs no input file. Each returns its exact item
+// count alongside the stream, ready for WithTotal, or -1 if the count does not
+// fit in an int64.
+
+// Range streams start, start+step, ... up to but excluding end, like a for
+// loop. step may be negative to count down; a zero step yields nothing.
+func Range(ctx context.Context, start, end, step int) (<-chan int, int64) {
+	// The span is computed modulo 2^64, which is exact for any pair of ints.
+	var n uint64
+	switch {
+	case step > 0 && end > start:
+		n = (uint64(end-start) + uint64(step) - 1) / uint64(step)
+	case step < 0 && end < start:
+		n = (uint64(start-end) + uint64(-step) - 1) / uint64(-step)
+	}
+	total := int64(-1)
+	if n <= math.MaxInt64 {
+		total = int64(n)
+	}
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		v := start
+		for i := uint64(0); i < n; i++ {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+			v += step
+		}
+	}()
+	return out, total
+}
+
+type cidrConfig struct {
+	shuffle bool
+	seed    uint64
+}
+
+// CIDROption configures CIDR.
+type CIDROption func(*cidrConfig)
+
+// WithShuffle makes CIDR visit the addresses in a pseudo-random order
+// determined by seed, so consecutive items don't hit neighbouring hosts. Every
+// address is still visited exactly once, and the same seed gives the same
+// order, so shards (see Shard) stay disjoint. Only prefixes with at most 64
+// host bits can be shuffled.
+func WithShuffle(seed uint64) CIDROption {
+	return func(c *cidrConfig) {
+		c.shuffle = true
+		c.seed = seed
+	}
+}
+
+// CIDR streams every address in prefix (e.g. "10.0.0.0/8" or "2001:db8::/120"),
+// network and broadcast addresses included, in ascending order unless
+// WithShuffle is given. A malformed prefix is reported as a *SourceError (see
+// WithErrors) and yields nothing.
+func CIDR(ctx context.Context, prefix string, opts ...CIDROption) (<-chan netip.Addr, int64) {
+	var cfg cidrConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan netip.Addr)
+	p, err := netip.ParsePrefix(prefix)
+	hostBits := p.Addr().BitLen() - p.Bits()
+	if err == nil && cfg.shuffle && hostBits > 64 {
+		err = errors.New("too many host bits to shuffle")
+	}
+	if err != nil {
+		go func() {
+			defer close(out)
+			reportErr(ctx, &SourceError{Path: prefix, Err: err})
+		}()
+		return out, 0
+	}
+	p = p.Masked()
+
+	total := int64(-1)
+	if hostBits < 63 {
+		total = int64(1) << hostBits
+	}
+	go func() {
+		defer close(out)
+		send := func(a netip.Addr) bool {
+			select {
+			case out <- a:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if !cfg.shuffle {
+			for a := p.Addr(); a.IsValid() && p.Contains(a); a = a.Next() {
+				if !send(a) {
+					return
+				}
+			}
+			return
+		}
+		perm := newPermutation(uint(hostBits), cfg.seed)
+		for i := uint64(0); ; i++ {
+			if !send(addOffset(p.Addr(), perm.next())) || i == perm.mask {
+				return
+			}
+		}
+	}()
+	return out, total
+}
+
+// permutation is a seeded bijection on [0, 2^k): a full-period linear
+// congruential sequence, scrambled by an invertible mixing step.
+type permutation struct {
+	k       uint
+	mask    uint64
+	a, c, x uint64
+}
+
+func newPermutation(k uint, seed uint64) *permutation {
+	mask := uint64(math.MaxUint64)
+	if k < 64 {
+		mask = 1<<k - 1
+	}
+	// a ≡ 5 (mod 8) and an odd c give a full period modulo any power of two.
+	return &permutation{
+		k:    k,
+		mask: mask,
+		a:    (mix64(seed)<<3 | 5) & mask,
+		c:    (mix64(seed+1) | 1) & mask,
+		x:    mix64(seed+2) & mask,
+	}
+}
+
+// next returns the next element; 2^k calls visit every value once.
+func (p *permutation) next() uint64 {
+	if p.k == 0 {
+		return 0
+	}
+	p.x = (p.a*p.x + p.c) & p.mask
+	// Xor-shifts and odd multiplications are bijections on k-bit values.
+	v := p.x
+	v ^= v >> (p.k/2 + 1)
+	v = (v * 0x9e3779b97f4a7c15) & p.mask
+	v ^= v >> (p.k/2 + 1)
+	return v
+}
+
+// mix64 is the splitmix64 finalizer, used to spread a seed over all bits.
+func mix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// addOffset returns base+off, for an off that keeps the result in base's prefix.
+func addOffset(base netip.Addr, off uint64) netip.Addr {
+	b := base.As16()
+	lo := binary.BigEndian.Uint64(b[8:])
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo, carry := bits.Add64(lo, off, 0)
+	hi += carry
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+	a := netip.AddrFrom16(b)
+	if base.Is4() {
+		return a.Unmap()
+	}
+	return a
+}
+
+// Product streams the cartesian product of lists: every combination taking one
+// element from each list, in lexicographic order (the last list varies
+// fastest). Each combination is a fresh slice the receiver may keep. It yields
+// nothing if no lists are given or any of them is empty.
+func Product[T any](ctx context.Context, lists ...[]T) (<-chan []T, int64) {
+	total := int64(0)
+	if len(lists) > 0 {
+		total = 1
+	}
+	for _, l := range lists {
+		switch {
+		case len(l) == 0:
+			total = 0
+		case total > math.MaxInt64/int64(len(l)):
+			total = -1
+		case total > 0:
+			total *= int64(len(l))
+		}
+		if total == 0 {
+			break
+		}
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		if total == 0 {
+			return
+		}
+		idx := make([]int, len(lists))
+		for {
+			combo := make([]T, len(lists))
+			for i, l := range lists {
+				combo[i] = l[idx[i]]
+			}
+			select {
+			case out <- combo:
+			case <-ctx.Done():
+				return
+			}
+			// Advance the odometer; done once the first list wraps around.
+			i := len(idx) - 1
+			for ; i >= 0; i-- {
+				idx[i]++
+				if idx[i] < len(lists[i]) {
+					break
+				}
+				idx[i] = 0
+			}
+			if i < 0 {
+				return
+			}
+		}
+	}()
+	return out, total
+}
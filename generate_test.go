@@ -0,0 +1,114 @@
+package gojob_test
+
+import (
+	"context"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestRange(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		start, end, step int
+		want             []int
+	}{
+		{0, 5, 2, []int{0, 2, 4}},
+		{5, 0, -2, []int{5, 3, 1}},
+		{0, 0, 1, nil},
+		{0, 5, 0, nil},
+		{0, 5, -1, nil},
+	}
+	for _, c := range cases {
+		ch, total := gojob.Range(ctx, c.start, c.end, c.step)
+		var got []int
+		for v := range ch {
+			got = append(got, v)
+		}
+		if !reflect.DeepEqual(got, c.want) || total != int64(len(c.want)) {
+			t.Errorf("Range(%d, %d, %d): want %v (total %d), got %v (total %d)", c.start, c.end, c.step, c.want, len(c.want), got, total)
+		}
+	}
+}
+
+func TestCIDR(t *testing.T) {
+	ctx := context.Background()
+	ch, total := gojob.CIDR(ctx, "192.168.1.5/30")
+	var got []string
+	for a := range ch {
+		got = append(got, a.String())
+	}
+	want := []string{"192.168.1.4", "192.168.1.5", "192.168.1.6", "192.168.1.7"}
+	if !reflect.DeepEqual(got, want) || total != 4 {
+		t.Errorf("want %v (total 4), got %v (total %d)", want, got, total)
+	}
+}
+
+func TestCIDRShuffle(t *testing.T) {
+	ctx := context.Background()
+	for _, prefix := range []string{"10.0.0.0/20", "2001:db8::/116", "10.0.0.1/32", "10.0.0.0/31"} {
+		p := netip.MustParsePrefix(prefix)
+		ch, total := gojob.CIDR(ctx, prefix, gojob.WithShuffle(42))
+		seen := map[netip.Addr]bool{}
+		sequential := true
+		var prev netip.Addr
+		for a := range ch {
+			if !p.Contains(a) || seen[a] {
+				t.Fatalf("%s: unexpected or repeated address %s", prefix, a)
+			}
+			if prev.IsValid() && a != prev.Next() {
+				sequential = false
+			}
+			seen[a], prev = true, a
+		}
+		if int64(len(seen)) != total {
+			t.Errorf("%s: visited %d addresses, total %d", prefix, len(seen), total)
+		}
+		if total > 16 && sequential {
+			t.Errorf("%s: shuffled order is sequential", prefix)
+		}
+	}
+
+	// The same seed gives the same order.
+	a, _ := gojob.CIDR(ctx, "10.0.0.0/24", gojob.WithShuffle(7))
+	b, _ := gojob.CIDR(ctx, "10.0.0.0/24", gojob.WithShuffle(7))
+	for x := range a {
+		if y := <-b; x != y {
+			t.Fatalf("same seed, different order: %s vs %s", x, y)
+		}
+	}
+}
+
+func TestCIDRInvalid(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	ch, total := gojob.CIDR(ctx, "10.0.0.0/33")
+	for range ch {
+		t.Error("expected no addresses")
+	}
+	if total != 0 || errs.Err() == nil {
+		t.Errorf("expected total 0 and a reported error, got %d, %v", total, errs.Err())
+	}
+}
+
+func TestProduct(t *testing.T) {
+	ctx := context.Background()
+	ch, total := gojob.Product(ctx, []string{"a", "b"}, []string{"1", "2", "3"})
+	var got []string
+	for combo := range ch {
+		got = append(got, combo[0]+combo[1])
+	}
+	want := []string{"a1", "a2", "a3", "b1", "b2", "b3"}
+	if !reflect.DeepEqual(got, want) || total != 6 {
+		t.Errorf("want %v (total 6), got %v (total %d)", want, got, total)
+	}
+
+	ch, total = gojob.Product(ctx, []string{"a"}, nil)
+	for range ch {
+		t.Error("expected no combinations with an empty list")
+	}
+	if total != 0 {
+		t.Errorf("expected total 0, got %d", total)
+	}
+}
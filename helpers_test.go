@@ -1,6 +1,13 @@
 package gojob_test
 
-import "github.com/WangYihang/gojob"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
 
 // collect drains a result stream into a slice.
 func collect[T any](in <-chan gojob.Result[T]) []gojob.Result[T] {
@@ -11,6 +18,21 @@ func collect[T any](in <-chan gojob.Result[T]) []gojob.Result[T] {
 	return out
 }
 
+// drain collects any stream into a slice.
+func drain[T any](in <-chan T) []T {
+	var out []T
+	for v := range in {
+		out = append(out, v)
+	}
+	return out
+}
+
+// intResults streams the Results of 0..n-1.
+func intResults(ctx context.Context, n int) <-chan gojob.Result[int] {
+	src, _ := gojob.Range(ctx, 0, n, 1)
+	return gojob.Process(ctx, src, func(ctx context.Context, i int) (int, error) { return i, nil }, gojob.WithWorkers(1))
+}
+
 // rangeInts returns []int{0, 1, ..., n-1}.
 func rangeInts(n int) []int {
 	out := make([]int, n)
@@ -19,3 +41,13 @@ func rangeInts(n int) []int {
 	}
 	return out
 }
+
+// writeFile writes content to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,438 @@
+package gojob
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type linesConfig struct {
+	maxSize   int
+	split     bufio.SplitFunc
+	keepSpace bool
+	skipEmpty bool
+	comment   string
+	files     int
+	poll      time.Duration
+}
+
+func linesDefaults() linesConfig {
+	return linesConfig{maxSize: bufio.MaxScanTokenSize, split: bufio.ScanLines, files: 1, poll: time.Second}
+}
+
+// LinesOption configures Lines, LinesMany, LinesGlob, and Follow.
+type LinesOption func(*linesConfig)
+
+// WithMaxLineSize sets the largest record Lines accepts, in bytes (default
+// 64 KiB). A longer record ends the source with a "token too long" error.
+// Non-positive values are ignored.
+func WithMaxLineSize(n int) LinesOption {
+	return func(c *linesConfig) {
+		if n > 0 {
+			c.maxSize = n
+		}
+	}
+}
+
+// WithSplit sets how the input is divided into records (default: newline
+// separated, as bufio.ScanLines). See ScanNUL and ScanParagraphs.
+func WithSplit(split bufio.SplitFunc) LinesOption {
+	return func(c *linesConfig) {
+		if split != nil {
+			c.split = split
+		}
+	}
+}
+
+// WithKeepSpace emits records as read instead of trimming surrounding
+// whitespace.
+func WithKeepSpace() LinesOption {
+	return func(c *linesConfig) {
+		c.keepSpace = true
+	}
+}
+
+// WithSkipEmpty drops records that are empty or all whitespace.
+func WithSkipEmpty() LinesOption {
+	return func(c *linesConfig) {
+		c.skipEmpty = true
+	}
+}
+
+// WithSkipComments drops records whose first non-whitespace text is prefix,
+// e.g. "#".
+func WithSkipComments(prefix string) LinesOption {
+	return func(c *linesConfig) {
+		c.comment = prefix
+	}
+}
+
+// WithConcurrentFiles lets LinesMany and LinesGlob read up to n files at once
+// (default 1). Records of one file stay in order, but records of different
+// files interleave. Non-positive values are ignored.
+func WithConcurrentFiles(n int) LinesOption {
+	return func(c *linesConfig) {
+		if n > 0 {
+			c.files = n
+		}
+	}
+}
+
+// WithPollInterval sets how often Follow checks its file for new data,
+// rotation, and truncation (default 1s). Non-positive values are ignored.
+func WithPollInterval(d time.Duration) LinesOption {
+	return func(c *linesConfig) {
+		if d > 0 {
+			c.poll = d
+		}
+	}
+}
+
+// ScanNUL is a bufio.SplitFunc for NUL-delimited records, as written by
+// find -print0 or xargs -0.
+func ScanNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return scanDelim(data, atEOF, []byte{0})
+}
+
+// ScanParagraphs is a bufio.SplitFunc for records separated by one or more
+// blank lines; a record keeps its inner newlines.
+func ScanParagraphs(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	for i := start; i < len(data); i++ {
+		if data[i] != '\n' {
+			continue
+		}
+		j := i + 1
+		if j < len(data) && data[j] == '\r' {
+			j++
+		}
+		if j < len(data) && data[j] == '\n' {
+			return j + 1, bytes.TrimRight(data[start:i], "\r"), nil
+		}
+	}
+	if atEOF && start < len(data) {
+		return len(data), bytes.TrimRight(data[start:], "\r\n"), nil
+	}
+	return start, nil, nil
+}
+
+func scanDelim(data []byte, atEOF bool, delim []byte) (int, []byte, error) {
+	if i := bytes.Index(data, delim); i >= 0 {
+		return i + len(delim), data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Lines streams the whitespace-trimmed lines of path, which may be a local
+// file, "-" for stdin, or any URL understood by uio (gzip, S3, ...). Options
+// change how records are split, trimmed, and filtered. If the source cannot be
+// opened or read, the channel closes early and the failure is reported as a
+// *SourceError (see WithErrors).
+func Lines(ctx context.Context, path string, opts ...LinesOption) <-chan string {
+	cfg := linesDefaults()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		f, err := openSource(path)
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Err: err})
+			return
+		}
+		defer f.Close()
+		n, err := scanLines(f, cfg, func(line string, _ int64) bool {
+			select {
+			case out <- line:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Line: n + 1, Err: err})
+		}
+	}()
+	return out
+}
+
+// LinesWithTotal is Lines plus a func reporting how many records it will
+// yield, for WithTotalFunc, so progress has a denominator and an ETA without
+// pre-counting the input by hand:
+//
+//	urls, total := gojob.LinesWithTotal(ctx, "urls.txt")
+//	results, stats := gojob.WithStats(ctx, gojob.Process(ctx, urls, fn), gojob.WithTotalFunc(total))
+//
+// For a plain local file the records are counted exactly by a background
+// pre-pass; until it finishes, and for gzip-compressed local files, the total
+// is estimated from the share of the file read so far. Other inputs (stdin,
+// remote URLs) report -1 until the stream ends. Once the stream ends, the
+// total is exact.
+func LinesWithTotal(ctx context.Context, path string, opts ...LinesOption) (<-chan string, func() int64) {
+	cfg := linesDefaults()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var exact, estimate atomic.Int64
+	exact.Store(-1)
+	estimate.Store(-1)
+	total := func() int64 {
+		if n := exact.Load(); n >= 0 {
+			return n
+		}
+		return estimate.Load()
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		in, err := openCounted(path)
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Err: err})
+			exact.Store(0)
+			return
+		}
+		defer in.Close()
+		if in.size >= 0 && !in.compressed {
+			go func() {
+				if n, ok := countLines(ctx, path, cfg); ok {
+					exact.CompareAndSwap(-1, n)
+				}
+			}()
+		}
+		var emitted int64
+		n, err := scanLines(in, cfg, func(line string, _ int64) bool {
+			select {
+			case out <- line:
+			case <-ctx.Done():
+				return false
+			}
+			emitted++
+			if read := in.read.Load(); in.size > 0 && read > 0 && read < in.size {
+				estimate.Store(max(emitted, int64(float64(emitted)*float64(in.size)/float64(read))))
+			}
+			return true
+		})
+		if err != nil {
+			reportErr(ctx, &SourceError{Path: path, Line: n + 1, Err: err})
+		}
+		if ctx.Err() == nil {
+			exact.Store(emitted)
+		}
+	}()
+	return out, total
+}
+
+// countLines counts the records of the local file at path that Lines would
+// yield under cfg.
+func countLines(ctx context.Context, path string, cfg linesConfig) (int64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	var count int64
+	_, err = scanLines(f, cfg, func(string, int64) bool {
+		count++
+		return ctx.Err() == nil
+	})
+	return count, err == nil && ctx.Err() == nil
+}
+
+// countedInput is an opened input that tracks how many bytes of the
+// underlying file have been consumed. size is -1 when it is unknown.
+type countedInput struct {
+	io.Reader
+	closer     io.Closer
+	size       int64
+	read       *atomic.Int64
+	compressed bool
+}
+
+func (c *countedInput) Close() error { return c.closer.Close() }
+
+// openCounted opens path as Lines does. Local files are opened directly, so the
+// bytes read can be compared with their size even when they are compressed;
+// anything else goes through uio with an unknown size.
+func openCounted(path string) (*countedInput, error) {
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme != "" || path == "-" {
+		f, err := openSource(path)
+		if err != nil {
+			return nil, err
+		}
+		return &countedInput{Reader: f, closer: f, size: -1, read: new(atomic.Int64)}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	in := &countedInput{closer: f, size: fi.Size(), read: new(atomic.Int64)}
+	cr := &countingReader{r: f, n: in.read}
+	in.Reader = cr
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".gzip") {
+		gz, err := gzip.NewReader(cr)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		in.Reader, in.compressed = gz, true
+	}
+	return in, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Line is a record from LinesMany or LinesGlob together with its provenance.
+type Line struct {
+	Text   string `json:"text"`
+	Path   string `json:"path"`
+	Number int64  `json:"line"` // 1-based record number within Path
+}
+
+// String returns the record's position as "path:line".
+func (l Line) String() string { return fmt.Sprintf("%s:%d", l.Path, l.Number) }
+
+// LinesMany streams the records of every path in turn, as Lines would, each
+// tagged with the file and line it came from. Files are read in the order
+// given unless WithConcurrentFiles allows several at once. A file that cannot
+// be opened or read is reported as a *SourceError (see WithErrors) and the
+// remaining files are still read.
+func LinesMany(ctx context.Context, paths []string, opts ...LinesOption) <-chan Line {
+	cfg := linesDefaults()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan Line)
+	work := From(ctx, paths...)
+	var wg sync.WaitGroup
+	wg.Add(cfg.files)
+	for i := 0; i < cfg.files; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range work {
+				if !linesOf(ctx, path, cfg, out) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// LinesGlob streams the records of every local file matching pattern (see
+// filepath.Match), in lexical order of their paths, as LinesMany does. Remote
+// locations cannot be listed; pass their URLs to LinesMany instead. A pattern
+// that is malformed or matches nothing is reported as a *SourceError.
+func LinesGlob(ctx context.Context, pattern string, opts ...LinesOption) <-chan Line {
+	paths, err := filepath.Glob(pattern)
+	if err == nil && len(paths) == 0 {
+		err = errors.New("no files match")
+	}
+	if err != nil {
+		out := make(chan Line)
+		go func() {
+			defer close(out)
+			reportErr(ctx, &SourceError{Path: pattern, Err: err})
+		}()
+		return out
+	}
+	return LinesMany(ctx, paths, opts...)
+}
+
+// linesOf sends the records of path to out, reporting any error. It returns
+// false once ctx is cancelled.
+func linesOf(ctx context.Context, path string, cfg linesConfig, out chan<- Line) bool {
+	f, err := openSource(path)
+	if err != nil {
+		reportErr(ctx, &SourceError{Path: path, Err: err})
+		return ctx.Err() == nil
+	}
+	defer f.Close()
+	n, err := scanLines(f, cfg, func(text string, n int64) bool {
+		select {
+		case out <- Line{Text: text, Path: path, Number: n}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	if err != nil {
+		reportErr(ctx, &SourceError{Path: path, Line: n + 1, Err: err})
+	}
+	return ctx.Err() == nil
+}
+
+// scanLines splits r into records according to cfg and calls emit with each
+// one that survives the filters, along with its 1-based record number, until
+// emit returns false or r is exhausted. It returns the number of records read
+// and the read error, if any.
+func scanLines(r io.Reader, cfg linesConfig, emit func(line string, n int64) bool) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(cfg.maxSize, 64*1024)), cfg.maxSize)
+	scanner.Split(cfg.split)
+	var n int64
+	for scanner.Scan() {
+		n++
+		line, ok := cfg.record(scanner.Text())
+		if !ok {
+			continue
+		}
+		if !emit(line, n) {
+			return n, nil
+		}
+	}
+	return n, scanner.Err()
+}
+
+// record applies the trimming and filtering options to a raw record, reporting
+// whether it should be emitted.
+func (c linesConfig) record(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if c.skipEmpty && trimmed == "" {
+		return "", false
+	}
+	if c.comment != "" && strings.HasPrefix(trimmed, c.comment) {
+		return "", false
+	}
+	if !c.keepSpace {
+		line = trimmed
+	}
+	return line, true
+}
@@ -0,0 +1,179 @@
+package gojob_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
+
+// readLines collects everything Lines yields for content under opts.
+func readLines(t *testing.T, content string, opts ...gojob.LinesOption) []string {
+	t.Helper()
+	path := writeFile(t, t.TempDir(), "input.txt", content)
+	var got []string
+	for line := range gojob.Lines(context.Background(), path, opts...) {
+		got = append(got, line)
+	}
+	return got
+}
+
+func TestLinesSkip(t *testing.T) {
+	got := readLines(t, "a\n  \n# comment\n  #indented\nb # not a comment\n",
+		gojob.WithSkipEmpty(), gojob.WithSkipComments("#"))
+	want := []string{"a", "b # not a comment"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestLinesKeepSpace(t *testing.T) {
+	got := readLines(t, "  a \n\tb\n", gojob.WithKeepSpace())
+	want := []string{"  a ", "\tb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestLinesSplit(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		split   bufio.SplitFunc
+		want    []string
+	}{
+		{"nul", "a b\x00c\nd\x00e", gojob.ScanNUL, []string{"a b", "c\nd", "e"}},
+		{"paragraphs", "\na\nb\n\n\nc\r\n\r\nd\n", gojob.ScanParagraphs, []string{"a\nb", "c", "d"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := readLines(t, c.content, gojob.WithSplit(c.split)); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLinesMaxLineSize(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	if got := readLines(t, "a\n"+long+"\n", gojob.WithMaxLineSize(1<<20)); len(got) != 2 || got[1] != long {
+		t.Errorf("expected the long line to be read whole, got %d lines", len(got))
+	}
+
+	ctx, errs := gojob.WithErrors(context.Background())
+	path := writeFile(t, t.TempDir(), "input.txt", "a\n"+long+"\n")
+	for range gojob.Lines(ctx, path) {
+	}
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Line != 2 {
+		t.Errorf("expected a too-long error on line 2 by default, got %v", errs.Err())
+	}
+}
+
+func TestLinesGlob(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	dir := t.TempDir()
+	writeFile(t, dir, "part-1.txt", "c\n")
+	writeFile(t, dir, "part-0.txt", "a\nb\n")
+	writeFile(t, dir, "other.txt", "x\n")
+
+	var got []string
+	for l := range gojob.LinesGlob(ctx, filepath.Join(dir, "part-*.txt")) {
+		got = append(got, filepath.Base(l.String())+"="+l.Text)
+	}
+	want := []string{"part-0.txt:1=a", "part-0.txt:2=b", "part-1.txt:1=c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if err := errs.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLinesGlobNoMatch(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	for range gojob.LinesGlob(ctx, filepath.Join(t.TempDir(), "*.txt")) {
+		t.Error("expected no records")
+	}
+	if errs.Err() == nil {
+		t.Error("expected an empty glob to be reported")
+	}
+}
+
+func TestLinesManyConcurrent(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 4; i++ {
+		paths = append(paths, writeFile(t, dir, strconv.Itoa(i)+".txt", "a\nb\nc\n"))
+	}
+	paths = append(paths, filepath.Join(dir, "missing", "x.txt"))
+
+	seen := map[string]bool{}
+	for l := range gojob.LinesMany(ctx, paths, gojob.WithConcurrentFiles(3)) {
+		seen[l.String()] = true
+	}
+	if len(seen) != 12 {
+		t.Errorf("expected 12 distinct records, got %d", len(seen))
+	}
+	if errs.Len() != 1 {
+		t.Errorf("expected the missing file to be reported once, got %d errors", errs.Len())
+	}
+}
+
+func TestLinesWithTotal(t *testing.T) {
+	ctx := context.Background()
+	var b strings.Builder
+	for i := range 1000 {
+		b.WriteString(strconv.Itoa(i) + "\n\n# comment\n")
+	}
+	path := writeFile(t, t.TempDir(), "input.txt", b.String())
+	lines, total := gojob.LinesWithTotal(ctx, path, gojob.WithSkipEmpty(), gojob.WithSkipComments("#"))
+	n := int64(0)
+	for range lines {
+		if got := total(); got != -1 && (got < n || got > 3000) {
+			t.Fatalf("implausible total %d after %d lines", got, n)
+		}
+		n++
+	}
+	if n != 1000 || total() != 1000 {
+		t.Errorf("got %d lines and total %d, want 1000", n, total())
+	}
+}
+
+func TestLinesWithTotalGzip(t *testing.T) {
+	ctx := context.Background()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	for i := range 100 {
+		zw.Write([]byte(strconv.Itoa(i) + "\n"))
+	}
+	zw.Close()
+	path := writeFile(t, t.TempDir(), "input.txt.gz", b.String())
+	lines, total := gojob.LinesWithTotal(ctx, path)
+	n := 0
+	for range lines {
+		n++
+	}
+	if n != 100 || total() != 100 {
+		t.Errorf("got %d lines and total %d, want 100", n, total())
+	}
+}
+
+func TestLinesWithTotalMissing(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	lines, total := gojob.LinesWithTotal(ctx, filepath.Join(t.TempDir(), "missing", "x.txt"))
+	for range lines {
+	}
+	if total() != 0 || errs.Len() != 1 {
+		t.Errorf("got total %d and %d errors, want 0 and 1", total(), errs.Len())
+	}
+}
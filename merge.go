@@ -0,0 +1,156 @@
+package gojob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type mergeConfig struct {
+	key      []string
+	dedup    bool
+	expected string
+}
+
+// MergeOption configures MergeJSONL.
+type MergeOption func(*mergeConfig)
+
+// WithMergeKey identifies the input item each record was produced from by a
+// dotted path into the record, e.g. "value.url". String values are used as-is;
+// any other JSON value is keyed by its compact encoding. A key is required by
+// WithDedup and WithExpected.
+func WithMergeKey(field string) MergeOption {
+	return func(c *mergeConfig) {
+		c.key = strings.Split(field, ".")
+	}
+}
+
+// WithDedup keeps only the first record seen for each key and drops the rest.
+func WithDedup() MergeOption {
+	return func(c *mergeConfig) {
+		c.dedup = true
+	}
+}
+
+// WithExpected names the original input (one item per line, in any form Lines
+// accepts). Every non-blank item whose key appears in no shard is reported in
+// MergeReport.Missing.
+func WithExpected(path string) MergeOption {
+	return func(c *mergeConfig) {
+		c.expected = path
+	}
+}
+
+// MergeReport summarizes a MergeJSONL run.
+type MergeReport struct {
+	Read       int64    `json:"read"`
+	Written    int64    `json:"written"`
+	Duplicates int64    `json:"duplicates"`
+	Missing    []string `json:"missing"`
+}
+
+// MergeJSONL concatenates the JSON Lines shard outputs at paths (local files,
+// "-" for stdin, or any URL understood by uio) into w, in the order given, and
+// reports how the shards cover the input. Blank lines are skipped; every other
+// line is copied verbatim. With WithMergeKey each line must be a JSON object
+// holding the key, and an error naming the file and line is returned if it is
+// not.
+//
+// Use it to consolidate the outputs of a job run with Shard on several
+// machines and to check that none of them silently lost its slice:
+//
+//	report, err := gojob.MergeJSONL(ctx, out, shards,
+//		gojob.WithMergeKey("value.url"),
+//		gojob.WithDedup(),
+//		gojob.WithExpected("urls.txt"),
+//	)
+func MergeJSONL(ctx context.Context, w io.Writer, paths []string, opts ...MergeOption) (MergeReport, error) {
+	var cfg mergeConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var report MergeReport
+	if cfg.key == nil && (cfg.dedup || cfg.expected != "") {
+		return report, errors.New("gojob: WithDedup and WithExpected require WithMergeKey")
+	}
+
+	seen := map[string]struct{}{}
+	for _, path := range paths {
+		err := eachLine(ctx, path, func(line []byte, n int64) error {
+			if len(bytes.TrimSpace(line)) == 0 {
+				return nil
+			}
+			report.Read++
+			if cfg.key != nil {
+				key, err := lookupKey(line, cfg.key)
+				if err != nil {
+					return &SourceError{Path: path, Line: n, Err: err}
+				}
+				if _, dup := seen[key]; dup && cfg.dedup {
+					report.Duplicates++
+					return nil
+				}
+				seen[key] = struct{}{}
+			}
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+			if line[len(line)-1] != '\n' {
+				if _, err := w.Write([]byte{'\n'}); err != nil {
+					return err
+				}
+			}
+			report.Written++
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if cfg.expected != "" {
+		err := eachLine(ctx, cfg.expected, func(line []byte, _ int64) error {
+			item := strings.TrimSpace(string(line))
+			if item == "" {
+				return nil
+			}
+			if _, ok := seen[item]; !ok {
+				report.Missing = append(report.Missing, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// lookupKey extracts the value at the dotted path from a JSON object line.
+func lookupKey(line []byte, path []string) (string, error) {
+	var raw json.RawMessage = line
+	for _, field := range path {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return "", fmt.Errorf("key %q: %w", strings.Join(path, "."), err)
+		}
+		v, ok := obj[field]
+		if !ok {
+			return "", fmt.Errorf("key %q: not found", strings.Join(path, "."))
+		}
+		raw = v
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,73 @@
+package gojob_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestMergeJSONL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	shards := []string{
+		writeFile(t, dir, "0.jsonl", `{"value":{"url":"a"},"error":""}`+"\n"+`{"value":{"url":"c"},"error":""}`+"\n"),
+		writeFile(t, dir, "1.jsonl", `{"value":{"url":"b"},"error":"boom"}`+"\n\n"+`{"value":{"url":"a"},"error":""}`),
+	}
+	input := writeFile(t, dir, "input.txt", "a\nb\nc\nd\n\ne\n")
+
+	var buf bytes.Buffer
+	report, err := gojob.MergeJSONL(ctx, &buf, shards,
+		gojob.WithMergeKey("value.url"),
+		gojob.WithDedup(),
+		gojob.WithExpected(input),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Read != 4 || report.Written != 3 || report.Duplicates != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if strings.Join(report.Missing, ",") != "d,e" {
+		t.Errorf("missing: want [d e], got %v", report.Missing)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Errorf("expected 3 merged lines, got %d: %q", len(lines), buf.String())
+	}
+}
+
+func TestMergeJSONLBadKey(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	shard := writeFile(t, dir, "0.jsonl", `{"value":{"url":"a"}}`+"\n"+`{"value":{}}`+"\n")
+	_, err := gojob.MergeJSONL(ctx, &bytes.Buffer{}, []string{shard}, gojob.WithMergeKey("value.url"))
+	if err == nil || !strings.Contains(err.Error(), "0.jsonl:2") {
+		t.Errorf("expected an error naming the file and line, got %v", err)
+	}
+}
+
+func TestMergeJSONLReadError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`{"value":1}` + "\n" + `{"value":2}` + "\n"))
+	zw.Close()
+	good := writeFile(t, dir, "0.jsonl", `{"value":0}`+"\n")
+	bad := writeFile(t, dir, "1.jsonl.gz", gz.String()[:gz.Len()-4]) // truncated
+	_, err := gojob.MergeJSONL(ctx, &bytes.Buffer{}, []string{good, bad})
+	var se *gojob.SourceError
+	if !errors.As(err, &se) || se.Path != bad || se.Line == 0 {
+		t.Errorf("expected an error naming the failing input and line, got %v", err)
+	}
+}
+
+func TestMergeJSONLRequiresKey(t *testing.T) {
+	if _, err := gojob.MergeJSONL(context.Background(), &bytes.Buffer{}, nil, gojob.WithDedup()); err == nil {
+		t.Error("expected WithDedup without WithMergeKey to fail")
+	}
+}
@@ -7,15 +7,38 @@ type config struct {
 	retries int
 	backoff BackoffFunc
 	timeout time.Duration
+	name    string
+
+	cache       any // a memo[In, Out], see WithCache
+	negativeTTL time.Duration
+
+	priority  any // a func(In) int, see WithPriority
+	lookahead int
+	aging     time.Duration
+
+	resources []any // of resourceNeed[In], see WithResource
 }
 
 func defaults() config {
-	return config{workers: 1, retries: 1}
+	return config{workers: 1, retries: 1, lookahead: 256}
 }
 
 // Option configures Process (and Execute).
+//
+// Option is not generic, so the options that are — WithCache, WithPriority,
+// WithResource and WithWeight — cannot be checked against the stage at
+// compile time: one whose types do not match the stage's input (and, for
+// WithCache, output) makes Process panic when it is called.
 type Option func(*config)
 
+func configure(opts []Option) config {
+	cfg := defaults()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
 // WithWorkers sets the number of concurrent workers (default 1). Non-positive
 // values are ignored.
 func WithWorkers(n int) Option {
@@ -47,6 +70,14 @@ func WithRetry(maxAttempts int, backoff BackoffFunc) Option {
 	}
 }
 
+// WithName names the stage in the pipeline's Topology (see WithTopology).
+// Process, ProcessInputs, Then, Execute and Shard take it.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
 // BackoffFunc returns how long to wait before a given retry attempt, where
 // attempt 1 is the delay before the second overall attempt.
 type BackoffFunc func(attempt int) time.Duration
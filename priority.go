@@ -0,0 +1,145 @@
+package gojob
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithPriority makes the stage run urgent items first: it reads ahead up to
+// WithLookahead items from its input (default 256) and hands the free worker
+// the one with the highest priority, oldest first among equals. Items that
+// arrive while the window is full wait their turn on the input, so priority
+// only reorders within the window. Items held in the window count as queued
+// on the stage (see StageSnapshot).
+//
+// Priority decides the order in which items reach the workers, not the order
+// in which they get resources: with WithResource or WithWeight, a worker waits
+// for room behind those already waiting, first come first served, so an
+// urgent item does not overtake the items the workers took before it.
+//
+//	results := gojob.Process(ctx, checks, probe, gojob.WithWorkers(32),
+//		gojob.WithPriority(func(c Check) int { return c.Tier }),
+//		gojob.WithAging(time.Minute))
+//
+// The stage's input type must match In, or Process panics.
+func WithPriority[In any](priority func(In) int) Option {
+	return func(c *config) {
+		c.priority = priority
+	}
+}
+
+// WithLookahead sets how many items WithPriority holds to choose from. A
+// larger window lets urgent items overtake more, at the cost of holding more
+// items in memory.
+func WithLookahead(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.lookahead = n
+		}
+	}
+}
+
+// WithAging raises the priority of a waiting item by one for every d it has
+// waited, so a steady stream of urgent items cannot starve the rest forever.
+func WithAging(d time.Duration) Option {
+	return func(c *config) {
+		c.aging = d
+	}
+}
+
+// priorityFor returns the priority func configured with WithPriority for a
+// stage reading In, or nil.
+func priorityFor[In any](cfg config) func(In) int {
+	if cfg.priority == nil {
+		return nil
+	}
+	p, ok := cfg.priority.(func(In) int)
+	if !ok {
+		var in In
+		panic(fmt.Sprintf("gojob: WithPriority does not match a stage reading %T", in))
+	}
+	return p
+}
+
+// prioritize reorders in: it holds up to lookahead items and passes on the
+// one with the highest priority whenever the consumer is ready for one. The
+// items it holds count as held by st.
+func prioritize[In any](ctx context.Context, in <-chan In, priority func(In) int, lookahead int, aging time.Duration, st *stage) <-chan In {
+	out := make(chan In)
+	go func() {
+		defer close(out)
+		var (
+			waiting prioHeap[In]
+			seq     int64
+			start   = time.Now()
+		)
+		defer func() { st.hold(-int64(waiting.Len())) }()
+		for in != nil || waiting.Len() > 0 {
+			var (
+				recvC <-chan In
+				sendC chan<- In
+				next  In
+			)
+			if in != nil && waiting.Len() < lookahead {
+				recvC = in
+			}
+			if waiting.Len() > 0 {
+				sendC, next = out, waiting[0].v
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-recvC:
+				if !ok {
+					in = nil
+					continue
+				}
+				// All waiting items age at the same rate, so counting an item's
+				// arrival against it ranks them as their current priorities would.
+				score := float64(priority(v))
+				if aging > 0 {
+					score -= float64(time.Since(start)) / float64(aging)
+				}
+				heap.Push(&waiting, prioItem[In]{v: v, score: score, seq: seq})
+				st.hold(1)
+				seq++
+			case sendC <- next:
+				heap.Pop(&waiting)
+				st.hold(-1)
+			}
+		}
+	}()
+	return out
+}
+
+type prioItem[In any] struct {
+	v     In
+	score float64
+	seq   int64 // arrival order, for ties
+}
+
+// prioHeap is a max-heap of items by score, then by arrival.
+type prioHeap[In any] []prioItem[In]
+
+func (h prioHeap[In]) Len() int { return len(h) }
+
+func (h prioHeap[In]) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score > h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h prioHeap[In]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *prioHeap[In]) Push(x any) { *h = append(*h, x.(prioItem[In])) }
+
+func (h *prioHeap[In]) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	old[len(old)-1] = prioItem[In]{}
+	*h = old[:len(old)-1]
+	return it
+}
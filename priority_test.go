@@ -0,0 +1,140 @@
+package gojob_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+type job struct {
+	id, prio int
+}
+
+// runOrder processes jobs with a single worker whose first call waits until
+// the scheduler has read the whole input (or as much as its window holds), and
+// returns the ids of the jobs run after that first one, in order.
+func runOrder(t *testing.T, jobs []job, opts ...gojob.Option) []int {
+	t.Helper()
+	ctx := context.Background()
+	src := make(chan job, len(jobs))
+	for _, j := range jobs {
+		src <- j
+	}
+	close(src)
+	var (
+		mu    sync.Mutex
+		order []int
+		first = true
+	)
+	fn := func(ctx context.Context, j job) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if first {
+			first = false
+			for deadline := time.Now().Add(100 * time.Millisecond); len(src) > 0 && time.Now().Before(deadline); {
+				time.Sleep(time.Millisecond)
+			}
+			return j.id, nil
+		}
+		order = append(order, j.id)
+		return j.id, nil
+	}
+	opts = append([]gojob.Option{gojob.WithPriority(func(j job) int { return j.prio })}, opts...)
+	if n := len(collect(gojob.Process(ctx, src, fn, opts...))); n != len(jobs) {
+		t.Fatalf("got %d results, want %d", n, len(jobs))
+	}
+	return order
+}
+
+func without(ids []int, drop int) []int {
+	var out []int
+	for _, id := range ids {
+		if id != drop {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func TestWithPriority(t *testing.T) {
+	jobs := []job{{0, 1}, {1, 5}, {2, 1}, {3, 9}, {4, 5}, {5, 0}}
+	order := runOrder(t, jobs)
+	if len(order) != len(jobs)-1 {
+		t.Fatalf("order = %v", order)
+	}
+	// Whichever job the worker took first, the rest run by priority, then
+	// arrival.
+	byPriority := []int{3, 1, 4, 0, 2, 5}
+	for _, first := range byPriority {
+		if !contains(order, first) {
+			if want := without(byPriority, first); !reflect.DeepEqual(order, want) {
+				t.Errorf("order = %v, want %v", order, want)
+			}
+			return
+		}
+	}
+}
+
+func TestWithPriorityAging(t *testing.T) {
+	// Aging by a nanosecond outweighs any priority, leaving arrival order.
+	jobs := []job{{0, 0}, {1, 0}, {2, 3}, {3, 1}, {4, 2}}
+	order := runOrder(t, jobs, gojob.WithAging(time.Nanosecond))
+	for i := 1; i < len(order); i++ {
+		if order[i] < order[i-1] {
+			t.Errorf("order = %v, want arrival order", order)
+		}
+	}
+}
+
+func TestWithLookahead(t *testing.T) {
+	// With a window of one there is nothing to choose from.
+	jobs := []job{{0, 0}, {1, 0}, {2, 3}, {3, 1}, {4, 2}}
+	order := runOrder(t, jobs, gojob.WithLookahead(1))
+	for i := 1; i < len(order); i++ {
+		if order[i] < order[i-1] {
+			t.Errorf("order = %v, want arrival order", order)
+		}
+	}
+}
+
+func TestWithPriorityTopology(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, topo := gojob.WithTopology(ctx)
+	src := make(chan int, 20)
+	for i := range 20 {
+		src <- i
+	}
+	close(src)
+	release := make(chan struct{})
+	results := gojob.Process(ctx, src, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, gojob.WithName("prio"), gojob.WithPriority(func(n int) int { return n }), gojob.WithLookahead(5))
+
+	// One item runs, five wait in the window and fourteen on the input.
+	waitFor(t, func() bool {
+		s, _ := stageByName(topo.Snapshot(), "prio")
+		return s.InFlight == 1 && s.Queued == 19
+	})
+	close(release)
+	if n := len(collect(results)); n != 20 {
+		t.Fatalf("got %d results, want 20", n)
+	}
+	if s, _ := stageByName(topo.Snapshot(), "prio"); s.Queued != 0 {
+		t.Errorf("prio = %+v after the run", s)
+	}
+}
+
+func contains(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
@@ -19,12 +19,99 @@ func Process[In, Out any](
 	fn func(context.Context, In) (Out, error),
 	opts ...Option,
 ) <-chan Result[Out] {
-	cfg := defaults()
-	for _, o := range opts {
-		o(&cfg)
+	cfg := configure(opts)
+	st := newStage(ctx, "process", cfg.name, cfg.workers, in)
+	return process(ctx, in, fn, cfg, st, func(_ In, r Result[Out]) Result[Out] { return r }, nil)
+}
+
+// ProcessInputs is Process with every Result carrying the input it was
+// produced from, as Value.Input, even when fn failed or timed out. Together
+// with SplitErrors it yields failures that can be fed straight back as the
+// input of a retry run (see ReadInputs):
+//
+//	ok, failed := gojob.SplitErrors(ctx, gojob.ProcessInputs(ctx, urls, fetch))
+func ProcessInputs[In, Out any](
+	ctx context.Context,
+	in <-chan In,
+	fn func(context.Context, In) (Out, error),
+	opts ...Option,
+) <-chan Result[Item[In, Out]] {
+	cfg := configure(opts)
+	st := newStage(ctx, "process", cfg.name, cfg.workers, in)
+	return process(ctx, in, fn, cfg, st, func(input In, r Result[Out]) Result[Item[In, Out]] {
+		return Result[Item[In, Out]]{
+			Value:     Item[In, Out]{Input: input, Output: r.Value},
+			Err:       r.Err,
+			Attempts:  r.Attempts,
+			StartedAt: r.StartedAt,
+			Duration:  r.Duration,
+			Cached:    r.Cached,
+		}
+	}, nil)
+}
+
+// Then runs the next stage of a pipeline: fn is applied, like Process, to the
+// Value of every successful result, while failed results skip it and pass
+// through with their original error and metadata. Attempts and Duration
+// accumulate over the stages and StartedAt is kept from the first, so a
+// multi-stage pipeline stays one expression:
+//
+//	pages := gojob.Process(ctx, urls, fetch, gojob.WithWorkers(64))
+//	docs := gojob.Then(ctx, pages, parse, gojob.WithWorkers(8))
+//	enriched := gojob.Then(ctx, docs, enrich, gojob.WithRetry(3, nil))
+//
+// opts configure this stage's workers, retries and timeout.
+func Then[In, Out any](
+	ctx context.Context,
+	in <-chan Result[In],
+	fn func(context.Context, In) (Out, error),
+	opts ...Option,
+) <-chan Result[Out] {
+	cfg := configure(opts)
+	if m := memoFor[In, Out](cfg); m != nil {
+		cfg.cache = resultMemo[In, Out]{m}
 	}
+	if p := priorityFor[In](cfg); p != nil {
+		cfg.priority = func(r Result[In]) int { return p(r.Value) }
+	}
+	for i, rn := range needsFor[In](cfg) {
+		cfg.resources[i] = resourceNeed[Result[In]]{r: rn.r, need: func(r Result[In]) int64 { return rn.need(r.Value) }}
+	}
+	st := newStage(ctx, "then", cfg.name, cfg.workers, in)
+	return process(ctx, in, func(ctx context.Context, r Result[In]) (Out, error) {
+		return fn(ctx, r.Value)
+	}, cfg, st, func(prev Result[In], r Result[Out]) Result[Out] {
+		r.Attempts += prev.Attempts
+		r.Duration += prev.Duration
+		if !prev.StartedAt.IsZero() {
+			r.StartedAt = prev.StartedAt
+		}
+		return r
+	}, func(prev Result[In]) (Result[Out], bool) {
+		return Result[Out]{Err: prev.Err, Attempts: prev.Attempts, StartedAt: prev.StartedAt, Duration: prev.Duration, Cached: prev.Cached}, prev.Err != nil
+	})
+}
 
-	out := make(chan Result[Out])
+// process is the worker pool behind Process, ProcessInputs and Then; emit
+// turns an input and its Result into the item sent downstream. If skip is
+// non-nil, inputs for which it returns true bypass fn and send what it
+// returns instead. The pool's activity is recorded against st.
+func process[In, Out, R any](
+	ctx context.Context,
+	in <-chan In,
+	fn func(context.Context, In) (Out, error),
+	cfg config,
+	st *stage,
+	emit func(In, Result[Out]) R,
+	skip func(In) (R, bool),
+) <-chan R {
+	out := make(chan R)
+	p := output(st, out)
+	m := memoFor[In, Out](cfg)
+	needs := needsFor[In](cfg)
+	if p := priorityFor[In](cfg); p != nil {
+		in = prioritize(ctx, in, p, cfg.lookahead, cfg.aging, st)
+	}
 	var wg sync.WaitGroup
 	wg.Add(cfg.workers)
 	for i := 0; i < cfg.workers; i++ {
@@ -38,10 +125,17 @@ func Process[In, Out any](
 					if !ok {
 						return
 					}
-					r := runOne(ctx, input, fn, cfg)
-					select {
-					case out <- r:
-					case <-ctx.Done():
+					st.received()
+					if skip != nil {
+						if r, ok := skip(input); ok {
+							if !emitTo(ctx, p, out, r) {
+								return
+							}
+							continue
+						}
+					}
+					r := emit(input, runCached(ctx, input, fn, cfg, m, needs, st))
+					if !emitTo(ctx, p, out, r) {
 						return
 					}
 				}
@@ -50,6 +144,7 @@ func Process[In, Out any](
 	}
 	go func() {
 		wg.Wait()
+		st.done()
 		close(out)
 	}()
 	return out
@@ -63,6 +158,34 @@ func Execute[T any](ctx context.Context, tasks <-chan Task[T], opts ...Option) <
 	}, opts...)
 }
 
+// runCached returns the cached Result for input if m has one, and otherwise
+// runs it and caches the outcome.
+func runCached[In, Out any](ctx context.Context, input In, fn func(context.Context, In) (Out, error), cfg config, m memo[In, Out], needs []resourceNeed[In], st *stage) Result[Out] {
+	if m == nil {
+		return runHeld(ctx, input, fn, cfg, needs, st)
+	}
+	return m.load(ctx, input, cfg.negativeTTL, func() Result[Out] {
+		return runHeld(ctx, input, fn, cfg, needs, st)
+	})
+}
+
+// runHeld runs input once the resources it needs are free. An item waiting
+// for resources counts as held by st, and as in flight only once it runs.
+func runHeld[In, Out any](ctx context.Context, input In, fn func(context.Context, In) (Out, error), cfg config, needs []resourceNeed[In], st *stage) Result[Out] {
+	if len(needs) > 0 {
+		st.hold(1)
+		release, err := acquireAll(ctx, needs, input)
+		st.hold(-1)
+		if err != nil {
+			return Result[Out]{Err: err, StartedAt: time.Now()}
+		}
+		defer release()
+	}
+	st.begin()
+	defer st.end()
+	return runOne(ctx, input, fn, cfg)
+}
+
 // runOne executes a single item with the configured retry and timeout policy,
 // recording the number of attempts and the wall-clock span across them.
 func runOne[In, Out any](ctx context.Context, input In, fn func(context.Context, In) (Out, error), cfg config) Result[Out] {
@@ -192,3 +192,76 @@ func TestProcessCancellation(t *testing.T) {
 		t.Fatal("Process did not terminate after cancellation")
 	}
 }
+
+func TestProcessInputs(t *testing.T) {
+	ctx := context.Background()
+	src := gojob.From(ctx, 1, 2, 3)
+	results := gojob.ProcessInputs(ctx, src, func(ctx context.Context, n int) (string, error) {
+		if n == 2 {
+			<-ctx.Done() // times out
+			return "", ctx.Err()
+		}
+		return fmt.Sprint(n * 10), nil
+	}, gojob.WithTimeout(20*time.Millisecond), gojob.WithRetry(2, nil))
+	got := map[int]gojob.Result[gojob.Item[int, string]]{}
+	for r := range results {
+		got[r.Value.Input] = r
+	}
+	if len(got) != 3 || got[1].Value.Output != "10" || got[3].Value.Output != "30" {
+		t.Errorf("unexpected results %+v", got)
+	}
+	if r := got[2]; !errors.Is(r.Err, context.DeadlineExceeded) || r.Attempts != 2 {
+		t.Errorf("the timed-out item should keep its input: %+v", r)
+	}
+}
+
+func TestThen(t *testing.T) {
+	ctx := context.Background()
+	fetched := gojob.Process(ctx, gojob.From(ctx, 1, 2, 3, 4), func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			return 0, errors.New("fetch failed")
+		}
+		return n, nil
+	})
+	attempts := map[int]int{}
+	var mu sync.Mutex
+	parsed := gojob.Then(ctx, fetched, func(ctx context.Context, n int) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[n]++
+		if n == 2 {
+			return "", errors.New("parse failed")
+		}
+		if n == 3 && attempts[n] == 1 {
+			return "", errors.New("flaky")
+		}
+		return fmt.Sprint(n), nil
+	}, gojob.WithRetry(2, nil))
+	results := drain(parsed)
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	byErr := map[string]gojob.Result[string]{}
+	for _, r := range results {
+		key := r.Value
+		if r.Err != nil {
+			key = r.Err.Error()
+		}
+		byErr[key] = r
+	}
+	if r, ok := byErr["fetch failed"]; !ok || r.Attempts != 1 {
+		t.Errorf("the fetch failure should pass through untouched: %+v", r)
+	}
+	if _, ran := attempts[1]; ran {
+		t.Error("the next stage ran on a failed result")
+	}
+	if r, ok := byErr["parse failed"]; !ok || r.Attempts != 3 {
+		t.Errorf("parse failure: want 1+2 attempts, got %+v", r)
+	}
+	if r, ok := byErr["3"]; !ok || r.Attempts != 3 || r.StartedAt.IsZero() {
+		t.Errorf("retried success: want 1+2 attempts, got %+v", r)
+	}
+	if r, ok := byErr["4"]; !ok || r.Attempts != 2 {
+		t.Errorf("success: want 1+1 attempts, got %+v", r)
+	}
+}
@@ -0,0 +1,183 @@
+package gojob
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Resource is a named, finite capacity — bytes of memory, CPU slots,
+// connections to a host — that items draw on while they run (see
+// WithResource). One Resource can be shared by several stages, bounding them
+// together. It is safe for concurrent use.
+type Resource struct {
+	id       int64
+	name     string
+	capacity int64
+
+	mu      sync.Mutex
+	used    int64
+	waiters list.List // of *resourceWaiter, in arrival order
+}
+
+type resourceWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+var resourceIDs atomic.Int64
+
+// NewResource returns a Resource of the given capacity.
+func NewResource(name string, capacity int64) *Resource {
+	return &Resource{id: resourceIDs.Add(1), name: name, capacity: max(capacity, 1)}
+}
+
+// Name returns the name the Resource was created with.
+func (r *Resource) Name() string { return r.name }
+
+// Capacity returns the Resource's capacity.
+func (r *Resource) Capacity() int64 { return r.capacity }
+
+// InUse returns how much of the Resource running items currently hold.
+func (r *Resource) InUse() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.used
+}
+
+// acquire takes n units, waiting until they are free or ctx is cancelled.
+// Requests are granted in arrival order, so a large one is not starved by a
+// stream of small ones.
+func (r *Resource) acquire(ctx context.Context, n int64) error {
+	r.mu.Lock()
+	if r.used+n <= r.capacity && r.waiters.Len() == 0 {
+		r.used += n
+		r.mu.Unlock()
+		return nil
+	}
+	w := &resourceWaiter{n: n, ready: make(chan struct{})}
+	el := r.waiters.PushBack(w)
+	r.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted meanwhile; give it back.
+			r.used -= n
+		default:
+			r.waiters.Remove(el)
+		}
+		r.grant()
+		r.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release returns n units.
+func (r *Resource) release(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.used -= n
+	r.grant()
+}
+
+// grant wakes the waiters at the front of the line that now fit.
+func (r *Resource) grant() {
+	for el := r.waiters.Front(); el != nil; el = r.waiters.Front() {
+		w := el.Value.(*resourceWaiter)
+		if r.used+w.n > r.capacity {
+			return
+		}
+		r.used += w.n
+		r.waiters.Remove(el)
+		close(w.ready)
+	}
+}
+
+// WithResource makes every item hold need(item) units of r while it runs; an
+// item starts only once all the resources of its stage have room for it, so
+// the stage runs as many items at once as fit, up to WithWorkers. Needs are
+// clamped to [0, capacity], so an item larger than the resource still runs,
+// alone. Waiting items get room in the order they asked for it, whatever
+// their priority (see WithPriority).
+//
+//	mem := gojob.NewResource("memory", 8<<30)
+//	conns := gojob.NewResource("connections", 16)
+//	results := gojob.Process(ctx, files, upload, gojob.WithWorkers(64),
+//		gojob.WithResource(mem, func(f File) int64 { return f.Size }),
+//		gojob.WithResource(conns, func(File) int64 { return 1 }))
+//
+// The stage's input type must match In, or Process panics.
+func WithResource[In any](r *Resource, need func(In) int64) Option {
+	return func(c *config) {
+		c.resources = append(c.resources, resourceNeed[In]{r: r, need: need})
+	}
+}
+
+// WithWeight bounds the total weight of the items running at once to
+// capacity, like a weighted semaphore: a shorthand for WithResource with a
+// resource of the stage's own.
+//
+//	results := gojob.Process(ctx, files, parse, gojob.WithWorkers(64),
+//		gojob.WithWeight(func(f File) int64 { return f.Size }, 4<<30))
+func WithWeight[In any](weight func(In) int64, capacity int64) Option {
+	return func(c *config) {
+		// Made here rather than above, so a stage reusing the option still
+		// gets a resource of its own.
+		WithResource(NewResource("weight", capacity), weight)(c)
+	}
+}
+
+type resourceNeed[In any] struct {
+	r    *Resource
+	need func(In) int64
+}
+
+// needsFor returns the resources configured for a stage reading In, in the
+// global order they are acquired in, which keeps stages sharing resources
+// from deadlocking.
+func needsFor[In any](cfg config) []resourceNeed[In] {
+	needs := make([]resourceNeed[In], 0, len(cfg.resources))
+	for _, rn := range cfg.resources {
+		n, ok := rn.(resourceNeed[In])
+		if !ok {
+			var in In
+			panic(fmt.Sprintf("gojob: WithResource does not match a stage reading %T", in))
+		}
+		needs = append(needs, n)
+	}
+	sort.Slice(needs, func(i, j int) bool { return needs[i].r.id < needs[j].r.id })
+	return needs
+}
+
+// acquireAll takes what input needs of every resource, returning the func
+// that gives it back.
+func acquireAll[In any](ctx context.Context, needs []resourceNeed[In], input In) (func(), error) {
+	held := make([]int64, len(needs))
+	release := func() {
+		for i, n := range held {
+			if n > 0 {
+				needs[i].r.release(n)
+			}
+		}
+	}
+	for i, rn := range needs {
+		n := min(max(rn.need(input), 0), rn.r.capacity)
+		if n == 0 {
+			continue
+		}
+		if err := rn.r.acquire(ctx, n); err != nil {
+			release()
+			return nil, err
+		}
+		held[i] = n
+	}
+	return release, nil
+}
@@ -0,0 +1,159 @@
+package gojob_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+// gauge tracks the peak of a running total.
+type gauge struct {
+	mu        sync.Mutex
+	cur, peak int64
+}
+
+func (g *gauge) add(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cur += n
+	g.peak = max(g.peak, g.cur)
+}
+
+func TestWithWeight(t *testing.T) {
+	ctx := context.Background()
+	var g gauge
+	sizes := []int64{4, 4, 4, 2, 2, 6, 1, 4, 25}
+	results := collect(gojob.Process(ctx, gojob.From(ctx, sizes...), func(ctx context.Context, n int64) (int64, error) {
+		g.add(min(n, 10))
+		time.Sleep(5 * time.Millisecond)
+		g.add(-min(n, 10))
+		return n, nil
+	}, gojob.WithWorkers(8), gojob.WithWeight(func(n int64) int64 { return n }, 10)))
+	if len(results) != len(sizes) {
+		t.Fatalf("got %d results, want %d (an oversized item must still run)", len(results), len(sizes))
+	}
+	if g.peak > 10 {
+		t.Errorf("peak weight in flight = %d, want <= 10", g.peak)
+	}
+	if g.peak < 6 {
+		t.Errorf("peak weight in flight = %d; items that fit together should run together", g.peak)
+	}
+}
+
+func TestWithResourceShared(t *testing.T) {
+	ctx := context.Background()
+	mem := gojob.NewResource("memory", 100)
+	conns := gojob.NewResource("connections", 2)
+	var memUse, connUse gauge
+	work := func(ctx context.Context, n int64) (int64, error) {
+		memUse.add(n)
+		connUse.add(1)
+		time.Sleep(2 * time.Millisecond)
+		memUse.add(-n)
+		connUse.add(-1)
+		return n, nil
+	}
+	opts := []gojob.Option{
+		gojob.WithWorkers(8),
+		gojob.WithResource(mem, func(n int64) int64 { return n }),
+		gojob.WithResource(conns, func(int64) int64 { return 1 }),
+	}
+	// Two stages drawing on the same resources are bounded together.
+	sizes := []int64{10, 60, 30, 50, 20, 70, 10, 10}
+	a := gojob.Process(ctx, gojob.From(ctx, sizes...), work, opts...)
+	b := gojob.Process(ctx, gojob.From(ctx, sizes...), work, opts...)
+	if n := len(collect(gojob.Merge(ctx, a, b))); n != 2*len(sizes) {
+		t.Fatalf("got %d results, want %d", n, 2*len(sizes))
+	}
+	if memUse.peak > 100 || connUse.peak > 2 {
+		t.Errorf("peaks: memory %d (cap 100), connections %d (cap 2)", memUse.peak, connUse.peak)
+	}
+	if mem.InUse() != 0 || conns.InUse() != 0 {
+		t.Errorf("resources still held after the run: %d, %d", mem.InUse(), conns.InUse())
+	}
+	if mem.Name() != "memory" || mem.Capacity() != 100 {
+		t.Errorf("mem = %s/%d", mem.Name(), mem.Capacity())
+	}
+}
+
+func TestWithResourceCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	slots := gojob.NewResource("slots", 1)
+	started := make(chan struct{}, 1)
+	results := gojob.Process(ctx, gojob.From(ctx, 1, 2), func(ctx context.Context, n int) (int, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, gojob.WithWorkers(2), gojob.WithResource(slots, func(int) int64 { return 1 }))
+	<-started
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		gojob.Drain(results)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a worker waiting for a resource did not stop on cancellation")
+	}
+	if slots.InUse() != 0 {
+		t.Errorf("%d slots still held", slots.InUse())
+	}
+}
+
+func TestWithWeightPerStage(t *testing.T) {
+	ctx := context.Background()
+	var g gauge
+	work := func(ctx context.Context, n int) (int, error) {
+		g.add(1)
+		// Wait a little for the other stage, which has its own capacity.
+		for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			g.mu.Lock()
+			cur := g.cur
+			g.mu.Unlock()
+			if cur >= 4 {
+				break
+			}
+		}
+		g.add(-1)
+		return n, nil
+	}
+	opts := []gojob.Option{gojob.WithWorkers(4), gojob.WithWeight(func(int) int64 { return 1 }, 2)}
+	a := gojob.Process(ctx, gojob.From(ctx, rangeInts(4)...), work, opts...)
+	b := gojob.Process(ctx, gojob.From(ctx, rangeInts(4)...), work, opts...)
+	gojob.Drain(gojob.Merge(ctx, a, b))
+	if g.peak != 4 {
+		t.Errorf("peak in flight = %d, want 2 per stage, 4 in all", g.peak)
+	}
+}
+
+func TestWithResourceTopology(t *testing.T) {
+	ctx, topo := gojob.WithTopology(context.Background())
+	slots := gojob.NewResource("slots", 1)
+	release := make(chan struct{})
+	results := gojob.Process(ctx, gojob.From(ctx, 1, 2, 3), func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, gojob.WithWorkers(3), gojob.WithResource(slots, func(int) int64 { return 1 }))
+	// Workers waiting for the slot are not busy: they hold queued items.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := topo.Snapshot().Stages[0]
+		if s.InFlight == 1 && s.Queued == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d in flight and %d queued, want 1 and 2", s.InFlight, s.Queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	gojob.Drain(results)
+}
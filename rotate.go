@@ -0,0 +1,298 @@
+package gojob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/WangYihang/uio"
+)
+
+type rotateConfig struct {
+	maxBytes   int64
+	maxRecords int64
+	maxAge     time.Duration
+	onClose    func(path string)
+}
+
+// RotateOption configures WriteRotating and WritePartitioned.
+type RotateOption func(*rotateConfig)
+
+// WithMaxBytes starts a new file once the current one holds n bytes of JSON
+// (counted before compression).
+func WithMaxBytes(n int64) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithMaxRecords starts a new file once the current one holds n results.
+func WithMaxRecords(n int64) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxRecords = n
+	}
+}
+
+// WithMaxAge closes a file once it has been open for d, even if no further
+// results arrive for it, so a quiet partition still yields finished files.
+func WithMaxAge(d time.Duration) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxAge = d
+	}
+}
+
+// WithOnClose calls fn with the path of every file once it is complete and
+// closed, e.g. to upload it while the job continues. fn runs on the writing
+// goroutine, so a slow fn holds up the stream.
+func WithOnClose(fn func(path string)) RotateOption {
+	return func(c *rotateConfig) {
+		c.onClose = fn
+	}
+}
+
+// WriteRotating writes the stream as JSON Lines to a series of files rather
+// than one that grows for the whole run: a new file is started whenever one of
+// WithMaxBytes, WithMaxRecords or WithMaxAge is reached. Files are opened with
+// uio, so a ".gz" pattern is gzip-compressed and an s3:// pattern is uploaded
+// as each file closes.
+//
+// pattern names the files. "{seq}" is replaced by a per-file sequence number
+// (00000, 00001, ...) and "{time}" by the UTC time the file was opened
+// (20060102T150405Z); if pattern has no "{seq}", "-{seq}" is inserted before
+// its extension, so "out/results.jsonl.gz" yields out/results-00000.jsonl.gz
+// and so on, and files opened within the same second still get distinct
+// names. Existing local files of the same name are replaced.
+//
+// Like WriteJSONL, it returns the first error, the ctx error once ctx is
+// cancelled, or the source errors collected in ctx (see WithErrors) once the
+// stream ends. Open files are closed in all cases.
+func WriteRotating[T any](ctx context.Context, pattern string, in <-chan Result[T], opts ...RotateOption) error {
+	return writeRotating(ctx, pattern, in, nil, opts)
+}
+
+// WritePartitioned is WriteRotating with a separate series of files for every
+// key that key returns, e.g. one per status code or error class:
+//
+//	err := gojob.WritePartitioned(ctx, "out/results.jsonl.gz", results, func(r gojob.Result[Page]) string {
+//		return strconv.Itoa(r.Value.Status)
+//	}, gojob.WithMaxAge(time.Hour))
+//
+// "{key}" in pattern is replaced by the key; without it, files go into a
+// directory per key (out/200/results-00000.jsonl.gz). Characters other than
+// letters, digits, '-', '_', '.' and '=' are replaced by '_' in keys, and an
+// empty key becomes "_". Every key keeps a file open, so keys should come from
+// a small set.
+func WritePartitioned[T any](ctx context.Context, pattern string, in <-chan Result[T], key func(Result[T]) string, opts ...RotateOption) error {
+	if key == nil {
+		key = func(Result[T]) string { return "" }
+	}
+	return writeRotating(ctx, pattern, in, key, opts)
+}
+
+func writeRotating[T any](ctx context.Context, pattern string, in <-chan Result[T], key func(Result[T]) string, opts []RotateOption) error {
+	var cfg rotateConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	r := &rotator[T]{cfg: cfg, pattern: rotatePattern(pattern, key != nil), files: map[string]*segment{}, seq: map[string]int{}}
+
+	var tick <-chan time.Time
+	if cfg.maxAge > 0 {
+		t := time.NewTicker(min(cfg.maxAge, time.Second))
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			r.closeAll()
+			return ctx.Err()
+		case <-tick:
+			if err := r.expire(time.Now()); err != nil {
+				r.closeAll()
+				return err
+			}
+		case res, ok := <-in:
+			if !ok {
+				if err := r.closeAll(); err != nil {
+					return err
+				}
+				return sourceErr(ctx)
+			}
+			k := ""
+			if key != nil {
+				k = partitionKey(key(res))
+			}
+			if err := r.write(k, res); err != nil {
+				r.closeAll()
+				return err
+			}
+		}
+	}
+}
+
+// rotatePattern makes sure pattern distinguishes the files of a series and,
+// when partitioning, those of different keys.
+func rotatePattern(pattern string, partitioned bool) string {
+	name, query, hasQuery := strings.Cut(pattern, "?")
+	dir, base := "", name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		dir, base = name[:i+1], name[i+1:]
+	}
+	if !strings.Contains(name, "{seq}") {
+		if i := strings.Index(base, "."); i > 0 {
+			base = base[:i] + "-{seq}" + base[i:]
+		} else {
+			base += "-{seq}"
+		}
+	}
+	if partitioned && !strings.Contains(name, "{key}") {
+		dir += "{key}/"
+	}
+	if hasQuery {
+		return dir + base + "?" + query
+	}
+	return dir + base
+}
+
+// partitionKey makes a key safe to use in a file name.
+func partitionKey(k string) string {
+	if k == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.=", r):
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+type rotator[T any] struct {
+	cfg     rotateConfig
+	pattern string
+	files   map[string]*segment
+	seq     map[string]int
+}
+
+// segment is an open file of a series.
+type segment struct {
+	path    string
+	w       io.WriteCloser
+	n       *countingWriter
+	enc     *json.Encoder
+	records int64
+	opened  time.Time
+}
+
+// write appends res to the open file for key, opening one first if needed and
+// closing it afterwards if it is full.
+func (r *rotator[T]) write(key string, res Result[T]) error {
+	s := r.files[key]
+	if s != nil && r.cfg.maxAge > 0 && time.Since(s.opened) >= r.cfg.maxAge {
+		if err := r.close(key); err != nil {
+			return err
+		}
+		s = nil
+	}
+	if s == nil {
+		var err error
+		if s, err = r.open(key); err != nil {
+			return err
+		}
+	}
+	if err := s.enc.Encode(res); err != nil {
+		return err
+	}
+	s.records++
+	if (r.cfg.maxRecords > 0 && s.records >= r.cfg.maxRecords) || (r.cfg.maxBytes > 0 && s.n.n >= r.cfg.maxBytes) {
+		return r.close(key)
+	}
+	return nil
+}
+
+func (r *rotator[T]) open(key string) (*segment, error) {
+	now := time.Now()
+	path := strings.NewReplacer(
+		"{key}", key,
+		"{seq}", fmt.Sprintf("%05d", r.seq[key]),
+		"{time}", now.UTC().Format("20060102T150405Z"),
+	).Replace(r.pattern)
+	r.seq[key]++
+
+	uri := path + "?mode=write"
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		if u.RawQuery != "" {
+			uri = path + "&mode=write"
+		}
+	} else if path != "-" {
+		// uio neither creates directories nor truncates local files.
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	w, err := uio.Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	cw := &countingWriter{w: w}
+	s := &segment{path: path, w: w, n: cw, enc: json.NewEncoder(cw), opened: now}
+	r.files[key] = s
+	return s, nil
+}
+
+func (r *rotator[T]) close(key string) error {
+	s := r.files[key]
+	delete(r.files, key)
+	if err := s.w.Close(); err != nil {
+		return err
+	}
+	if r.cfg.onClose != nil {
+		r.cfg.onClose(s.path)
+	}
+	return nil
+}
+
+// expire closes the files that have been open for longer than maxAge.
+func (r *rotator[T]) expire(now time.Time) error {
+	for key, s := range r.files {
+		if now.Sub(s.opened) >= r.cfg.maxAge {
+			if err := r.close(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closeAll closes every open file, returning the first error.
+func (r *rotator[T]) closeAll() error {
+	var first error
+	for key := range r.files {
+		if err := r.close(key); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
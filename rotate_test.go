@@ -0,0 +1,154 @@
+package gojob_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+// countLines maps each file under dir (relative path) to its number of lines.
+func countLines(t *testing.T, dir string) map[string]int {
+	t.Helper()
+	got := map[string]int{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if strings.HasSuffix(path, ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				return err
+			}
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		got[filepath.ToSlash(rel)] = strings.Count(string(b), "\n")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestWriteRotatingRecords(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var closed []string
+	err := gojob.WriteRotating(ctx, filepath.Join(dir, "results.jsonl.gz"), intResults(ctx, 25),
+		gojob.WithMaxRecords(10), gojob.WithOnClose(func(path string) { closed = append(closed, filepath.Base(path)) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"results-00000.jsonl.gz": 10, "results-00001.jsonl.gz": 10, "results-00002.jsonl.gz": 5}
+	if got := countLines(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := []string{"results-00000.jsonl.gz", "results-00001.jsonl.gz", "results-00002.jsonl.gz"}; !reflect.DeepEqual(closed, want) {
+		t.Errorf("closed %v, want %v", closed, want)
+	}
+}
+
+func TestWriteRotatingBytes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := gojob.WriteRotating(ctx, filepath.Join(dir, "r{seq}.jsonl"), intResults(ctx, 10), gojob.WithMaxBytes(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(t, dir); len(got) != 10 || got["r00009.jsonl"] != 1 {
+		t.Errorf("expected one file per result, got %v", got)
+	}
+}
+
+func TestWriteRotatingTimeWithinASecond(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// Ten files opened within the same second must not overwrite one another.
+	if err := gojob.WriteRotating(ctx, filepath.Join(dir, "r-{time}.jsonl"), intResults(ctx, 30), gojob.WithMaxRecords(3)); err != nil {
+		t.Fatal(err)
+	}
+	got := countLines(t, dir)
+	total := 0
+	for _, n := range got {
+		total += n
+	}
+	if len(got) != 10 || total != 30 {
+		t.Errorf("got %d files with %d lines, want 10 with 30: %v", len(got), total, got)
+	}
+}
+
+func TestWriteRotatingMaxAge(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	in := make(chan gojob.Result[int])
+	closed := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- gojob.WriteRotating(ctx, filepath.Join(dir, "r.jsonl"), in,
+			gojob.WithMaxAge(50*time.Millisecond), gojob.WithOnClose(func(path string) { closed <- path }))
+	}()
+	in <- gojob.Result[int]{Value: 1}
+	select {
+	case path := <-closed:
+		if filepath.Base(path) != "r-00000.jsonl" {
+			t.Errorf("unexpected file %s", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("an idle file was never closed")
+	}
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWritePartitioned(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	err := gojob.WritePartitioned(ctx, filepath.Join(dir, "out.jsonl"), intResults(ctx, 9), func(r gojob.Result[int]) string {
+		return []string{"even", "odd/../x", ""}[r.Value%3]
+	}, gojob.WithMaxRecords(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := countLines(t, dir)
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{
+		"_/out-00000.jsonl", "_/out-00001.jsonl",
+		"even/out-00000.jsonl", "even/out-00001.jsonl",
+		"odd_.._x/out-00000.jsonl", "odd_.._x/out-00001.jsonl",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got files %v, want %v", names, want)
+	}
+}
+
+func TestWriteRotatingSourceError(t *testing.T) {
+	ctx, _ := gojob.WithErrors(context.Background())
+	lines := gojob.Lines(ctx, filepath.Join(t.TempDir(), "missing", "in.txt"))
+	results := gojob.Process(ctx, lines, func(ctx context.Context, s string) (string, error) { return s, nil })
+	if err := gojob.WriteRotating(ctx, filepath.Join(t.TempDir(), "out.jsonl"), results); err == nil {
+		t.Error("expected the source error to be returned")
+	}
+}
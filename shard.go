@@ -6,21 +6,22 @@ import "context"
 // arrival order: the i-th item goes to shard i%numShards. It is the streaming
 // analogue of running numShards copies of a job on different machines, each
 // with a different shard index. A numShards of 1 (or less) returns in unchanged.
-func Shard[T any](ctx context.Context, in <-chan T, numShards, shard int) <-chan T {
+// Of the options, only WithName applies.
+func Shard[T any](ctx context.Context, in <-chan T, numShards, shard int, opts ...Option) <-chan T {
 	if numShards <= 1 {
 		return in
 	}
+	st := newStage(ctx, "shard", configure(opts).name, 0, in)
 	out := make(chan T)
+	p := output(st, out)
 	go func() {
 		defer close(out)
+		defer st.done()
 		i := 0
 		for v := range in {
-			if i%numShards == shard {
-				select {
-				case out <- v:
-				case <-ctx.Done():
-					return
-				}
+			st.received()
+			if i%numShards == shard && !emitTo(ctx, p, out, v) {
+				return
 			}
 			i++
 		}
@@ -2,29 +2,68 @@ package gojob
 
 import (
 	"context"
-	"encoding/json"
 	"io"
+	"sync/atomic"
+	"time"
 )
 
-// WriteJSONL encodes each result as one line of JSON to w until the stream ends
-// or ctx is cancelled, returning the first encode error (or the ctx error).
-func WriteJSONL[T any](ctx context.Context, w io.Writer, in <-chan Result[T]) error {
-	enc := json.NewEncoder(w)
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case r, ok := <-in:
-			if !ok {
-				return nil
-			}
-			if err := enc.Encode(r); err != nil {
-				return err
-			}
-		}
+type writeConfig struct {
+	bufSize  int
+	interval time.Duration
+	every    int64
+	sync     bool
+}
+
+// WriteOption configures WriteJSONL and Write.
+type WriteOption func(*writeConfig)
+
+// WithBufferSize buffers up to n bytes of output between flushes instead of
+// writing every result through to the writer (the default), which with a bare
+// *os.File costs a syscall per result. Pair it with WithFlushInterval or
+// WithFlushEvery to bound how long results can sit in the buffer.
+func WithBufferSize(n int) WriteOption {
+	return func(c *writeConfig) {
+		c.bufSize = n
+	}
+}
+
+// WithFlushInterval flushes the output every d.
+func WithFlushInterval(d time.Duration) WriteOption {
+	return func(c *writeConfig) {
+		c.interval = d
+	}
+}
+
+// WithFlushEvery flushes the output after every n results.
+func WithFlushEvery(n int64) WriteOption {
+	return func(c *writeConfig) {
+		c.every = n
+	}
+}
+
+// WithSync makes every flush also commit the output to stable storage, if the
+// writer has a Sync method (as *os.File does), so flushed results survive a
+// machine crash and not just a process crash.
+func WithSync() WriteOption {
+	return func(c *writeConfig) {
+		c.sync = true
 	}
 }
 
+// WriteJSONL encodes each result as one line of JSON to w until the stream ends
+// or ctx is cancelled, returning the first encode error (or the ctx error). If
+// ctx carries an Errors (see WithErrors), the source errors it collected are
+// returned once the stream ends, so a source that failed fails the sink too.
+//
+// opts trade write cost against durability. Whatever they are, once WriteJSONL
+// returns without a write error — because the stream ended or ctx was
+// cancelled — every result it took from in has been flushed to w (and synced,
+// with WithSync), while results it did not take are still in the channel. A
+// checkpoint taken after it returns can therefore rely on the output.
+func WriteJSONL[T any](ctx context.Context, w io.Writer, in <-chan Result[T], opts ...WriteOption) error {
+	return Write(ctx, w, in, JSONL[T](), opts...)
+}
+
 // Drain consumes and discards a stream. Useful for a tee'd branch that has no
 // sink of its own.
 func Drain[T any](in <-chan Result[T]) {
@@ -32,33 +71,198 @@ func Drain[T any](in <-chan Result[T]) {
 	}
 }
 
+// DropPolicy decides what a Tee branch does with a result when its buffer is
+// full.
+type DropPolicy int
+
+const (
+	// Block waits for the branch to catch up, holding up every branch (the
+	// default).
+	Block DropPolicy = iota
+	// DropNewest discards the incoming result.
+	DropNewest
+	// DropOldest discards the oldest buffered result to make room.
+	DropOldest
+)
+
+type branchConfig struct {
+	buffer  int
+	policy  DropPolicy
+	rate    float64
+	dropped *atomic.Int64
+}
+
+// BranchOption configures one output of Tee; see WithBranch.
+type BranchOption func(*branchConfig)
+
+// WithBuffer lets up to n results wait for the branch's consumer.
+func WithBuffer(n int) BranchOption {
+	return func(c *branchConfig) {
+		if n > 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// WithDropPolicy sets what the branch does when its buffer is full. With
+// DropNewest or DropOldest the branch never holds up the others, so a
+// best-effort observer (a dashboard, a sampler) cannot throttle the main sink.
+// Such a branch buffers at least one result.
+func WithDropPolicy(p DropPolicy) BranchOption {
+	return func(c *branchConfig) {
+		c.policy = p
+	}
+}
+
+// WithSampleRate passes only the given fraction of results, in (0, 1], to the
+// branch, spread evenly over the stream: 0.01 passes every hundredth result.
+// Results left out by sampling are not counted as dropped.
+func WithSampleRate(rate float64) BranchOption {
+	return func(c *branchConfig) {
+		if rate > 0 && rate <= 1 {
+			c.rate = rate
+		}
+	}
+}
+
+// WithDropCounter also adds the number of results the branch drops to n, which
+// the caller may read at any time, e.g. to report them in Stats (see
+// WithDropped). Drops are always recorded in the pipeline's Topology (see
+// WithTopology).
+func WithDropCounter(n *atomic.Int64) BranchOption {
+	return func(c *branchConfig) {
+		c.dropped = n
+	}
+}
+
+type teeConfig struct {
+	branches map[int][]BranchOption
+	name     string
+}
+
+// TeeOption configures Tee.
+type TeeOption func(*teeConfig)
+
+// WithBranch applies opts to the i-th output of Tee (0-based):
+//
+//	var dropped atomic.Int64
+//	outs := gojob.Tee(ctx, results, 2, gojob.WithBranch(1,
+//		gojob.WithBuffer(1024), gojob.WithDropPolicy(gojob.DropOldest), gojob.WithDropCounter(&dropped)))
+//	go web.Serve(ctx, stats, ":8080") // fed from outs[1]
+func WithBranch(i int, opts ...BranchOption) TeeOption {
+	return func(c *teeConfig) {
+		c.branches[i] = append(c.branches[i], opts...)
+	}
+}
+
+// WithTeeName names the Tee stage in the pipeline's Topology (see
+// WithTopology).
+func WithTeeName(name string) TeeOption {
+	return func(c *teeConfig) {
+		c.name = name
+	}
+}
+
 // Tee duplicates the input into n independent streams; every result is
-// delivered to all of them. Each output applies backpressure, so all of them
-// must be consumed. The outputs close when the input does or ctx is cancelled.
-func Tee[T any](ctx context.Context, in <-chan Result[T], n int) []<-chan Result[T] {
-	outs := make([]chan Result[T], n)
-	for i := range outs {
-		outs[i] = make(chan Result[T])
+// delivered to all of them. By default each output applies backpressure, so all
+// of them must be consumed and the slowest one sets the pace; WithBranch makes
+// an output buffered, lossy or sampled instead. The outputs close when the
+// input does or ctx is cancelled.
+func Tee[T any](ctx context.Context, in <-chan Result[T], n int, opts ...TeeOption) []<-chan Result[T] {
+	tc := teeConfig{branches: map[int][]BranchOption{}}
+	for _, o := range opts {
+		o(&tc)
+	}
+	st := newStage(ctx, "tee", tc.name, 0, in)
+	branches := make([]*teeBranch[T], n)
+	for i := range branches {
+		var cfg branchConfig
+		for _, o := range tc.branches[i] {
+			o(&cfg)
+		}
+		if cfg.policy != Block {
+			cfg.buffer = max(cfg.buffer, 1)
+		}
+		b := &teeBranch[T]{cfg: cfg, ch: make(chan Result[T], cfg.buffer)}
+		b.port = output(st, b.ch)
+		branches[i] = b
 	}
 	go func() {
 		defer func() {
-			for _, o := range outs {
-				close(o)
+			st.done()
+			for _, b := range branches {
+				close(b.ch)
 			}
 		}()
 		for r := range in {
-			for _, o := range outs {
-				select {
-				case o <- r:
-				case <-ctx.Done():
+			st.received()
+			for _, b := range branches {
+				if !b.send(ctx, r) {
 					return
 				}
 			}
+			st.passed()
 		}
 	}()
 	ro := make([]<-chan Result[T], n)
-	for i, o := range outs {
-		ro[i] = o
+	for i, b := range branches {
+		ro[i] = b.ch
 	}
 	return ro
 }
+
+type teeBranch[T any] struct {
+	cfg  branchConfig
+	ch   chan Result[T]
+	port *port
+	seen int64 // results offered, for sampling
+}
+
+// send offers r to the branch according to its policy, returning false once ctx
+// is cancelled.
+func (b *teeBranch[T]) send(ctx context.Context, r Result[T]) bool {
+	if b.cfg.rate > 0 {
+		// Keep the k-th result when floor(k*rate) steps up, which spreads the
+		// kept results evenly.
+		k := b.seen
+		b.seen++
+		if int64(float64(k+1)*b.cfg.rate) == int64(float64(k)*b.cfg.rate) {
+			return true
+		}
+	}
+	switch b.cfg.policy {
+	case DropNewest:
+		select {
+		case b.ch <- r:
+			b.port.sent()
+		default:
+			b.drop()
+		}
+	case DropOldest:
+		for {
+			select {
+			case b.ch <- r:
+				b.port.sent()
+				return true
+			default:
+			}
+			select {
+			case <-b.ch:
+				b.drop()
+			default:
+			}
+		}
+	default:
+		if !emitTo(ctx, b.port, b.ch, r) {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+func (b *teeBranch[T]) drop() {
+	b.port.drop()
+	if b.cfg.dropped != nil {
+		b.cfg.dropped.Add(1)
+	}
+}
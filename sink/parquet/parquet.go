@@ -0,0 +1,273 @@
+// Package parquet writes gojob result streams to Apache Parquet files, for
+// result sets too large to load comfortably as JSON Lines into DuckDB, Spark
+// or pandas. Like prom and redisq, it lives in its own package so that
+// importing gojob does not pull in a Parquet implementation.
+//
+// Each result becomes a row with the columns value, error, attempts,
+// started_at and duration_ms, mirroring Result.MarshalJSON. The schema of the
+// value column is derived from T: a struct becomes a group of columns, named
+// and tuned with `parquet` struct tags (see github.com/parquet-go/parquet-go).
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/WangYihang/gojob"
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// Compression selects the codec column chunks are compressed with.
+type Compression int
+
+const (
+	Snappy Compression = iota
+	Zstd
+	Gzip
+	Uncompressed
+)
+
+func (c Compression) codec() compress.Codec {
+	switch c {
+	case Zstd:
+		return &pq.Zstd
+	case Gzip:
+		return &pq.Gzip
+	case Uncompressed:
+		return &pq.Uncompressed
+	default:
+		return &pq.Snappy
+	}
+}
+
+type config struct {
+	rowGroup    int64
+	compression Compression
+	rollEvery   int64
+}
+
+// Option configures Write and NewEncoder.
+type Option func(*config)
+
+// WithRowGroupSize sets how many rows go into a row group (default 128k). Larger
+// groups compress and scan better but take more memory to write.
+func WithRowGroupSize(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.rowGroup = n
+		}
+	}
+}
+
+// WithCompression sets the compression codec (default Snappy).
+func WithCompression(codec Compression) Option {
+	return func(c *config) {
+		c.compression = codec
+	}
+}
+
+// WithRollEvery makes Write start a new file every n rows, so a run that dies
+// leaves every finished file readable. It has no effect on NewEncoder.
+func WithRollEvery(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.rollEvery = n
+		}
+	}
+}
+
+func defaults(opts []Option) config {
+	cfg := config{rowGroup: 128 << 10}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// row is the on-disk shape of a Result.
+type row[T any] struct {
+	Value      T      `parquet:"value"`
+	Error      string `parquet:"error"`
+	Attempts   int64  `parquet:"attempts"`
+	StartedAt  int64  `parquet:"started_at,timestamp(microsecond)"`
+	DurationMs int64  `parquet:"duration_ms"`
+}
+
+// batchSize is how many rows an encoder buffers before handing them to the
+// Parquet writer, which is much faster than writing them one by one.
+const batchSize = 1024
+
+// NewEncoder returns a gojob.Encoder that writes the whole stream to w as a
+// single Parquet file, completed by the final Flush:
+//
+//	err := gojob.Write(ctx, out, results, parquet.NewEncoder[Page](parquet.WithCompression(parquet.Zstd)))
+//
+// A T that has no Parquet representation (such as an interface or a func) makes
+// the first Encode fail.
+func NewEncoder[T any](opts ...Option) gojob.Encoder[T] {
+	return &encoder[T]{cfg: defaults(opts)}
+}
+
+type encoder[T any] struct {
+	cfg config
+	w   *pq.GenericWriter[row[T]]
+	buf []row[T]
+}
+
+func (e *encoder[T]) Encode(w io.Writer, r gojob.Result[T]) error {
+	if err := e.start(w); err != nil {
+		return err
+	}
+	var errStr string
+	if r.Err != nil {
+		errStr = r.Err.Error()
+	}
+	e.buf = append(e.buf, row[T]{
+		Value:      r.Value,
+		Error:      errStr,
+		Attempts:   int64(r.Attempts),
+		StartedAt:  r.StartedAt.UnixMicro(),
+		DurationMs: r.Duration.Milliseconds(),
+	})
+	if len(e.buf) >= batchSize {
+		return e.writeBuffered()
+	}
+	return nil
+}
+
+func (e *encoder[T]) Flush(w io.Writer) error {
+	if err := e.start(w); err != nil {
+		return err
+	}
+	if err := e.writeBuffered(); err != nil {
+		return err
+	}
+	return e.w.Close()
+}
+
+func (e *encoder[T]) writeBuffered() error {
+	_, err := e.w.Write(e.buf)
+	clear(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+// start creates the Parquet writer on first use. The schema is derived from T
+// by reflection, which panics on unsupported types; that is turned into an
+// error.
+func (e *encoder[T]) start(w io.Writer) (err error) {
+	if e.w != nil {
+		return nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("parquet: no schema for %T: %v", *new(T), p)
+		}
+	}()
+	e.w = pq.NewGenericWriter[row[T]](w,
+		pq.MaxRowsPerRowGroup(e.cfg.rowGroup),
+		pq.Compression(e.cfg.compression.codec()),
+	)
+	e.buf = make([]row[T], 0, batchSize)
+	return nil
+}
+
+// Write writes the stream to the Parquet file at path until it ends or ctx is
+// cancelled, returning the first error. Like gojob.WriteJSONL, it returns the
+// source errors collected in ctx (see gojob.WithErrors) once the stream ends.
+//
+// With WithRollEvery, the rows are spread over files named after path with a
+// part number before the extension: results-00000.parquet,
+// results-00001.parquet, and so on. A file is written under a ".tmp" name and
+// renamed once complete, so every file with its final name is readable, even
+// if the run dies; when ctx is cancelled the file in progress is completed.
+func Write[T any](ctx context.Context, path string, in <-chan gojob.Result[T], opts ...Option) error {
+	return gojob.Write(ctx, io.Discard, in, &fileEncoder[T]{cfg: defaults(opts), path: path})
+}
+
+// fileEncoder writes to files of its own, ignoring the writer gojob.Write
+// passes it.
+type fileEncoder[T any] struct {
+	cfg  config
+	path string
+	part int
+	rows int64
+	f    *os.File
+	enc  *encoder[T]
+}
+
+func (e *fileEncoder[T]) Encode(_ io.Writer, r gojob.Result[T]) error {
+	if e.f == nil {
+		if err := e.open(); err != nil {
+			return err
+		}
+	}
+	if err := e.enc.Encode(e.f, r); err != nil {
+		e.abort()
+		return err
+	}
+	e.rows++
+	if e.cfg.rollEvery > 0 && e.rows >= e.cfg.rollEvery {
+		return e.finish()
+	}
+	return nil
+}
+
+func (e *fileEncoder[T]) Flush(io.Writer) error {
+	if e.f == nil {
+		if e.part > 0 {
+			return nil // the last file was completed by rolling
+		}
+		// An empty stream still gets a file, so readers find the schema.
+		if err := e.open(); err != nil {
+			return err
+		}
+	}
+	return e.finish()
+}
+
+func (e *fileEncoder[T]) name() string {
+	if e.cfg.rollEvery <= 0 {
+		return e.path
+	}
+	ext := filepath.Ext(e.path)
+	return fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(e.path, ext), e.part, ext)
+}
+
+func (e *fileEncoder[T]) open() error {
+	f, err := os.Create(e.name() + ".tmp")
+	if err != nil {
+		return err
+	}
+	e.f, e.rows = f, 0
+	e.enc = &encoder[T]{cfg: e.cfg}
+	return nil
+}
+
+// finish completes the current file and moves it to its final name.
+func (e *fileEncoder[T]) finish() error {
+	if err := e.enc.Flush(e.f); err != nil {
+		e.abort()
+		return err
+	}
+	if err := e.f.Close(); err != nil {
+		e.abort()
+		return err
+	}
+	name := e.name()
+	e.f = nil
+	e.part++
+	return os.Rename(name+".tmp", name)
+}
+
+// abort discards the file in progress after an error.
+func (e *fileEncoder[T]) abort() {
+	e.f.Close()
+	os.Remove(e.f.Name())
+	e.f = nil
+}
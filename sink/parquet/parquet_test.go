@@ -0,0 +1,127 @@
+package parquet_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+	"github.com/WangYihang/gojob/sink/parquet"
+	pq "github.com/parquet-go/parquet-go"
+)
+
+type page struct {
+	URL    string `parquet:"url"`
+	Status int    `parquet:"status"`
+}
+
+// record mirrors the rows the package writes.
+type record struct {
+	Value      page   `parquet:"value"`
+	Error      string `parquet:"error"`
+	Attempts   int64  `parquet:"attempts"`
+	StartedAt  int64  `parquet:"started_at,timestamp(microsecond)"`
+	DurationMs int64  `parquet:"duration_ms"`
+}
+
+func results(n int) []gojob.Result[page] {
+	rs := make([]gojob.Result[page], n)
+	for i := range rs {
+		rs[i] = gojob.Result[page]{
+			Value:     page{URL: "https://example.com/" + string(rune('a'+i%26)), Status: 200 + i},
+			Attempts:  1,
+			StartedAt: time.UnixMicro(int64(i)),
+			Duration:  time.Duration(i) * time.Millisecond,
+		}
+	}
+	rs[0].Err = errors.New("boom")
+	return rs
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	if err := parquet.Write(ctx, path, gojob.From(ctx, results(3000)...), parquet.WithRowGroupSize(1000), parquet.WithCompression(parquet.Zstd)); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := pq.ReadFile[record](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3000 {
+		t.Fatalf("got %d rows, want 3000", len(rows))
+	}
+	if r := rows[0]; r.Error != "boom" || r.Value.Status != 200 {
+		t.Errorf("unexpected first row %+v", r)
+	}
+	if r := rows[2999]; r.Value.Status != 3199 || r.StartedAt != 2999 || r.DurationMs != 2999 {
+		t.Errorf("unexpected last row %+v", r)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestWriteRollEvery(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := parquet.Write(ctx, filepath.Join(dir, "out.parquet"), gojob.From(ctx, results(25)...), parquet.WithRollEvery(10)); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"out-00000.parquet": 10, "out-00001.parquet": 10, "out-00002.parquet": 5} {
+		rows, err := pq.ReadFile[record](filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != want {
+			t.Errorf("%s: got %d rows, want %d", name, len(rows), want)
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 3 {
+		t.Errorf("got %d files, want 3", len(files))
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	if err := parquet.Write(ctx, path, gojob.From[gojob.Result[page]](ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := pq.ReadFile[record](path); err != nil || len(rows) != 0 {
+		t.Errorf("got %d rows, %v; want an empty file", len(rows), err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if err := gojob.Write(ctx, &buf, gojob.From(ctx, results(5)...), parquet.NewEncoder[page]()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := pq.Read[record](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(rows) != 5 {
+		t.Fatalf("got %d rows, %v", len(rows), err)
+	}
+}
+
+func TestEncoderUnsupportedType(t *testing.T) {
+	ctx := context.Background()
+	in := gojob.From(ctx, gojob.Result[func()]{})
+	if err := gojob.Write(ctx, &bytes.Buffer{}, in, parquet.NewEncoder[func()]()); err == nil {
+		t.Error("expected an error for a type without a Parquet schema")
+	}
+}
+
+func TestWriteSourceError(t *testing.T) {
+	ctx, _ := gojob.WithErrors(context.Background())
+	lines := gojob.Lines(ctx, filepath.Join(t.TempDir(), "missing", "in.txt"))
+	results := gojob.Process(ctx, lines, func(ctx context.Context, s string) (page, error) { return page{URL: s}, nil })
+	if err := parquet.Write(ctx, filepath.Join(t.TempDir(), "out.parquet"), results); err == nil {
+		t.Error("expected the source error to be returned")
+	}
+}
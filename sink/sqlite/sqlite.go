@@ -0,0 +1,378 @@
+// Package sqlite stores gojob result streams in a SQLite database, so results
+// can be queried while the job runs ("how many 5xx so far from host X?"). It
+// uses a pure-Go SQLite driver, so it builds without cgo, and lives in its own
+// package so that importing gojob does not pull the driver in.
+//
+// Each result becomes a row of a table whose columns are derived from T: a
+// struct contributes a column per exported field, named with `db` struct tags
+// (`db:"-"` skips a field); any other T becomes a single "value" column. They
+// are followed by the error (NULL on success), attempts, started_at and
+// duration_ms columns.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/WangYihang/gojob"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// Open opens the SQLite database at path, creating it if needed, in WAL mode
+// so that other connections (such as the sqlite3 shell) can read while a Write
+// is in progress.
+func Open(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+}
+
+type config struct {
+	batch    int
+	interval time.Duration
+	key      []string
+}
+
+// Option configures Write.
+type Option func(*config)
+
+// WithBatchSize sets how many rows are inserted per transaction (default 1000).
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.batch = n
+		}
+	}
+}
+
+// WithCommitInterval commits a partial batch once it is d old (default 1s), so
+// queries see recent results even when they trickle in.
+func WithCommitInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithKey makes the given columns the table's primary key and turns inserts
+// into upserts: a result whose key is already stored replaces the earlier row,
+// so rerunning a job (or its failures) overwrites earlier attempts.
+func WithKey(columns ...string) Option {
+	return func(c *config) {
+		c.key = append(c.key, columns...)
+	}
+}
+
+// Write inserts the stream into table, creating the table if it does not
+// exist, until the stream ends or ctx is cancelled. Rows are inserted in
+// batched transactions; once Write returns, every result it took from in has
+// been committed, even when ctx was cancelled. Like gojob.WriteJSONL, it
+// returns the first error, the ctx error, or the source errors collected in
+// ctx (see gojob.WithErrors) once the stream ends.
+//
+//	db, err := sqlite.Open("results.db")
+//	...
+//	err = sqlite.Write(ctx, db, "pages", results, sqlite.WithKey("url"))
+func Write[T any](ctx context.Context, db *sql.DB, table string, in <-chan gojob.Result[T], opts ...Option) error {
+	cfg := config{batch: 1000, interval: time.Second}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	cols, err := resultColumns[T]()
+	if err != nil {
+		return err
+	}
+	if err := checkKey(cols, cfg.key); err != nil {
+		return err
+	}
+	// Commits must complete even once ctx is cancelled.
+	dbCtx := context.WithoutCancel(ctx)
+	if _, err := db.ExecContext(dbCtx, createTable(table, cols, cfg.key)); err != nil {
+		return err
+	}
+	insert := insertRow(table, cols, cfg.key)
+
+	var batch [][]any
+	commit := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		tx, err := db.BeginTx(dbCtx, nil)
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.PrepareContext(dbCtx, insert)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, args := range batch {
+			if _, err := stmt.ExecContext(dbCtx, args...); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := commit(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if err := commit(); err != nil {
+				return err
+			}
+		case r, ok := <-in:
+			if !ok {
+				if err := commit(); err != nil {
+					return err
+				}
+				return gojob.ErrorsFrom(ctx).Err()
+			}
+			args := make([]any, len(cols))
+			for i, c := range cols {
+				v, err := c.value(r)
+				if err != nil {
+					return fmt.Errorf("sqlite: column %q: %w", c.name, err)
+				}
+				args[i] = v
+			}
+			batch = append(batch, args)
+			if len(batch) >= cfg.batch {
+				if err := commit(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// column is a table column and how to compute it from a result.
+type column[T any] struct {
+	name  string
+	typ   string
+	value func(gojob.Result[T]) (any, error)
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// resultColumns lists the columns of a Result[T]: the fields of the Value, then
+// the metadata.
+func resultColumns[T any]() ([]column[T], error) {
+	var cols []column[T]
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	st, ptr := t, false
+	if st.Kind() == reflect.Pointer && st.Elem().Kind() == reflect.Struct {
+		st, ptr = st.Elem(), true
+	}
+	if st.Kind() == reflect.Struct && st != timeType && !st.Implements(textMarshalerType) && !reflect.PointerTo(st).Implements(textMarshalerType) {
+		for _, sf := range reflect.VisibleFields(st) {
+			if !sf.IsExported() || sf.Anonymous {
+				continue
+			}
+			name := sf.Name
+			if tag, ok := sf.Tag.Lookup("db"); ok {
+				tag, _, _ = strings.Cut(tag, ",")
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			index := sf.Index
+			cols = append(cols, column[T]{name: name, typ: sqlType(sf.Type), value: func(r gojob.Result[T]) (any, error) {
+				v := reflect.ValueOf(&r.Value).Elem()
+				if ptr {
+					if v.IsNil() {
+						return nil, nil
+					}
+					v = v.Elem()
+				}
+				f, err := v.FieldByIndexErr(index)
+				if err != nil {
+					return nil, nil // through a nil embedded pointer
+				}
+				return sqlValue(f)
+			}})
+		}
+	} else {
+		cols = append(cols, column[T]{name: "value", typ: sqlType(t), value: func(r gojob.Result[T]) (any, error) {
+			return sqlValue(reflect.ValueOf(&r.Value).Elem())
+		}})
+	}
+	cols = append(cols,
+		column[T]{name: "error", typ: "TEXT", value: func(r gojob.Result[T]) (any, error) {
+			if r.Err == nil {
+				return nil, nil
+			}
+			return r.Err.Error(), nil
+		}},
+		column[T]{name: "attempts", typ: "INTEGER", value: func(r gojob.Result[T]) (any, error) {
+			return int64(r.Attempts), nil
+		}},
+		column[T]{name: "started_at", typ: "TEXT", value: func(r gojob.Result[T]) (any, error) {
+			if r.StartedAt.IsZero() {
+				return nil, nil
+			}
+			return r.StartedAt.UTC().Format(time.RFC3339Nano), nil
+		}},
+		column[T]{name: "duration_ms", typ: "INTEGER", value: func(r gojob.Result[T]) (any, error) {
+			return r.Duration.Milliseconds(), nil
+		}},
+	)
+	seen := map[string]bool{}
+	for _, c := range cols {
+		if seen[c.name] {
+			return nil, fmt.Errorf("sqlite: duplicate column %q", c.name)
+		}
+		seen[c.name] = true
+	}
+	return cols, nil
+}
+
+// sqlType is the column type for values of type t. Times are stored as RFC
+// 3339 text, which SQLite's date functions understand, and durations as
+// integer nanoseconds; types without a natural SQL counterpart are stored as
+// JSON text, which SQLite's JSON functions can query.
+func sqlType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return "TEXT"
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB"
+		}
+	}
+	return "TEXT"
+}
+
+// sqlValue converts f to a value the driver stores as sqlType(f.Type()).
+func sqlValue(f reflect.Value) (any, error) {
+	if f.Kind() == reflect.Pointer || f.Kind() == reflect.Interface {
+		if f.IsNil() {
+			return nil, nil
+		}
+		if f.Kind() == reflect.Interface {
+			b, err := json.Marshal(f.Interface())
+			return string(b), err
+		}
+	}
+	if t, ok := f.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	if m, ok := f.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if f.Kind() == reflect.Pointer {
+		f = f.Elem()
+	}
+	switch f.Kind() {
+	case reflect.Bool:
+		return f.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return f.Float(), nil
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Slice:
+		if f.Type().Elem().Kind() == reflect.Uint8 {
+			return f.Bytes(), nil
+		}
+	}
+	b, err := json.Marshal(f.Interface())
+	return string(b), err
+}
+
+func checkKey[T any](cols []column[T], key []string) error {
+next:
+	for _, k := range key {
+		for _, c := range cols {
+			if c.name == k {
+				continue next
+			}
+		}
+		return fmt.Errorf("sqlite: no key column %q", k)
+	}
+	return nil
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func createTable[T any](table string, cols []column[T], key []string) string {
+	defs := make([]string, 0, len(cols)+1)
+	for _, c := range cols {
+		defs = append(defs, quote(c.name)+" "+c.typ)
+	}
+	if len(key) > 0 {
+		quoted := make([]string, len(key))
+		for i, k := range key {
+			quoted[i] = quote(k)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quote(table), strings.Join(defs, ", "))
+}
+
+func insertRow[T any](table string, cols []column[T], key []string) string {
+	names := make([]string, len(cols))
+	marks := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = quote(c.name)
+		marks[i] = "?"
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(table), strings.Join(names, ", "), strings.Join(marks, ", "))
+	if len(key) == 0 {
+		return q
+	}
+	isKey := map[string]bool{}
+	quoted := make([]string, len(key))
+	for i, k := range key {
+		isKey[k] = true
+		quoted[i] = quote(k)
+	}
+	var set []string
+	for _, c := range cols {
+		if !isKey[c.name] {
+			set = append(set, quote(c.name)+" = excluded."+quote(c.name))
+		}
+	}
+	q += " ON CONFLICT (" + strings.Join(quoted, ", ") + ")"
+	if len(set) == 0 {
+		return q + " DO NOTHING"
+	}
+	return q + " DO UPDATE SET " + strings.Join(set, ", ")
+}
@@ -0,0 +1,131 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+	"github.com/WangYihang/gojob/sink/sqlite"
+)
+
+type page struct {
+	URL     string        `db:"url"`
+	Host    string        `db:"host"`
+	Status  int           `db:"status"`
+	Latency time.Duration `db:"latency_ns"`
+	Tags    []string      `db:"tags"`
+	Secret  string        `db:"-"`
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	results := []gojob.Result[page]{
+		{Value: page{URL: "https://a/1", Host: "a", Status: 200, Latency: time.Millisecond, Tags: []string{"x"}}, Attempts: 1, StartedAt: time.Unix(0, 0)},
+		{Value: page{URL: "https://a/2", Host: "a", Status: 503}, Err: errors.New("unavailable"), Attempts: 3},
+		{Value: page{URL: "https://b/1", Host: "b", Status: 500}, Attempts: 1},
+	}
+	if err := sqlite.Write(ctx, db, "pages", gojob.From(ctx, results...), sqlite.WithBatchSize(2)); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT count(*) FROM pages WHERE host = 'a' AND status >= 500`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("got %d rows (%v), want 1", n, err)
+	}
+	var tags, started string
+	var latency int64
+	if err := db.QueryRow(`SELECT tags, latency_ns, started_at FROM pages WHERE url = 'https://a/1'`).Scan(&tags, &latency, &started); err != nil {
+		t.Fatal(err)
+	}
+	if tags != `["x"]` || latency != int64(time.Millisecond) || started != "1970-01-01T00:00:00Z" {
+		t.Errorf("unexpected row: tags %s, latency %d, started_at %s", tags, latency, started)
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM pages WHERE error IS NULL`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("got %d successes (%v), want 2", n, err)
+	}
+}
+
+func TestWriteUpsert(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	first := gojob.From(ctx,
+		gojob.Result[page]{Value: page{URL: "u1"}, Err: errors.New("timeout"), Attempts: 3},
+		gojob.Result[page]{Value: page{URL: "u2", Status: 200}, Attempts: 1})
+	if err := sqlite.Write(ctx, db, "pages", first, sqlite.WithKey("url")); err != nil {
+		t.Fatal(err)
+	}
+	rerun := gojob.From(ctx, gojob.Result[page]{Value: page{URL: "u1", Status: 200}, Attempts: 1})
+	if err := sqlite.Write(ctx, db, "pages", rerun, sqlite.WithKey("url")); err != nil {
+		t.Fatal(err)
+	}
+	var n, status int
+	if err := db.QueryRow(`SELECT count(*) FROM pages`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("got %d rows (%v), want 2", n, err)
+	}
+	var errStr *string
+	if err := db.QueryRow(`SELECT status, error FROM pages WHERE url = 'u1'`).Scan(&status, &errStr); err != nil {
+		t.Fatal(err)
+	}
+	if status != 200 || errStr != nil {
+		t.Errorf("rerun did not overwrite the failed attempt: status %d, error %v", status, errStr)
+	}
+}
+
+func TestWriteCommitsWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	in := make(chan gojob.Result[int])
+	done := make(chan error)
+	go func() { done <- sqlite.Write(ctx, db, "nums", in, sqlite.WithCommitInterval(10*time.Millisecond)) }()
+	in <- gojob.Result[int]{Value: 1}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var n int
+		if err := db.QueryRow(`SELECT count(*) FROM nums`).Scan(&n); err == nil && n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch was never committed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	in <- gojob.Result[int]{Value: 2}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var sum int
+	if err := db.QueryRow(`SELECT sum(value) FROM nums`).Scan(&sum); err != nil || sum != 3 {
+		t.Errorf("results taken before cancellation were not committed: sum %d (%v)", sum, err)
+	}
+}
+
+func TestWriteUnknownKey(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := sqlite.Write(ctx, db, "pages", gojob.From[gojob.Result[page]](ctx), sqlite.WithKey("nope")); err == nil {
+		t.Error("expected an error for an unknown key column")
+	}
+}
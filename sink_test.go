@@ -8,6 +8,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -100,3 +101,142 @@ func TestTeeCancelled(t *testing.T) {
 		t.Fatal("Tee outputs did not close after cancellation")
 	}
 }
+
+func TestWriteJSONLSourceError(t *testing.T) {
+	ctx, _ := gojob.WithErrors(context.Background())
+	lines := gojob.Lines(ctx, "/nonexistent-dir-gojob/missing.txt")
+	results := gojob.Process(ctx, lines, func(ctx context.Context, s string) (string, error) { return s, nil })
+	var se *gojob.SourceError
+	if err := gojob.WriteJSONL(ctx, io.Discard, results); !errors.As(err, &se) {
+		t.Errorf("expected the source error to fail the sink, got %v", err)
+	}
+}
+
+// syncWriter records the writes and syncs it receives.
+type syncWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	syncs  int
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.syncs++
+	return nil
+}
+
+func (w *syncWriter) counts() (lines, writes, syncs int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Count(w.buf.String(), "\n"), w.writes, w.syncs
+}
+
+func TestWriteJSONLFlushEvery(t *testing.T) {
+	ctx := context.Background()
+	var w syncWriter
+	if err := gojob.WriteJSONL(ctx, &w, intResults(ctx, 10), gojob.WithBufferSize(1<<16), gojob.WithFlushEvery(4), gojob.WithSync()); err != nil {
+		t.Fatal(err)
+	}
+	// Flushes after results 4 and 8, and at the end.
+	if lines, writes, syncs := w.counts(); lines != 10 || writes != 3 || syncs != 3 {
+		t.Errorf("got %d lines, %d writes, %d syncs; want 10, 3, 3", lines, writes, syncs)
+	}
+}
+
+func TestWriteJSONLFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	var w syncWriter
+	in := make(chan gojob.Result[int])
+	done := make(chan error)
+	go func() {
+		done <- gojob.WriteJSONL(ctx, &w, in, gojob.WithBufferSize(1<<16), gojob.WithFlushInterval(10*time.Millisecond))
+	}()
+	in <- gojob.Result[int]{Value: 1}
+	deadline := time.Now().Add(5 * time.Second)
+	for lines, _, _ := w.counts(); lines != 1; lines, _, _ = w.counts() {
+		if time.Now().After(deadline) {
+			t.Fatal("buffered result was never flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteJSONLCancelledFlushes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var w syncWriter
+	in := make(chan gojob.Result[int])
+	done := make(chan error)
+	go func() {
+		done <- gojob.WriteJSONL(ctx, &w, in, gojob.WithBufferSize(1<<16), gojob.WithSync())
+	}()
+	in <- gojob.Result[int]{Value: 1}
+	in <- gojob.Result[int]{Value: 2}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	// Every result taken from the stream is durable once WriteJSONL returns.
+	if lines, _, syncs := w.counts(); lines != 2 || syncs != 1 {
+		t.Errorf("got %d lines and %d syncs, want 2 and 1", lines, syncs)
+	}
+}
+
+func TestTeeDropPolicies(t *testing.T) {
+	ctx := context.Background()
+	var newest, oldest atomic.Int64
+	outs := gojob.Tee(ctx, intResults(ctx, 100), 3,
+		gojob.WithBranch(1, gojob.WithBuffer(10), gojob.WithDropPolicy(gojob.DropNewest), gojob.WithDropCounter(&newest)),
+		gojob.WithBranch(2, gojob.WithBuffer(10), gojob.WithDropPolicy(gojob.DropOldest), gojob.WithDropCounter(&oldest)))
+
+	// Only the main branch is consumed until the stream ends: the lossy
+	// branches must not hold it up.
+	main := 0
+	for range outs[0] {
+		main++
+	}
+	if main != 100 {
+		t.Fatalf("main branch got %d results, want 100", main)
+	}
+	var kept [3][]int
+	for i := 1; i < 3; i++ {
+		for r := range outs[i] {
+			kept[i] = append(kept[i], r.Value)
+		}
+	}
+	if len(kept[1]) != 10 || kept[1][0] != 0 || newest.Load() != 90 {
+		t.Errorf("DropNewest kept %v, dropped %d", kept[1], newest.Load())
+	}
+	if len(kept[2]) != 10 || kept[2][0] != 90 || oldest.Load() != 90 {
+		t.Errorf("DropOldest kept %v, dropped %d", kept[2], oldest.Load())
+	}
+	if _, stats := gojob.WithStats(ctx, gojob.From[gojob.Result[int]](ctx), gojob.WithDropped(&newest)); stats.Snapshot().Dropped != 90 {
+		t.Errorf("stats dropped = %d, want 90", stats.Snapshot().Dropped)
+	}
+}
+
+func TestTeeSampleRate(t *testing.T) {
+	ctx := context.Background()
+	outs := gojob.Tee(ctx, intResults(ctx, 100), 2, gojob.WithBranch(1, gojob.WithSampleRate(0.1), gojob.WithBuffer(100)))
+	for range outs[0] {
+	}
+	n := 0
+	for range outs[1] {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("sampled %d results, want 10", n)
+	}
+}
@@ -2,10 +2,12 @@ package gojob
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
-	"log/slog"
-	"strings"
+	"net/url"
 
 	"github.com/WangYihang/uio"
 )
@@ -26,30 +28,107 @@ func From[T any](ctx context.Context, items ...T) <-chan T {
 	return out
 }
 
-// Lines streams the whitespace-trimmed lines of path, which may be a local
-// file, "-" for stdin, or any URL understood by uio (gzip, S3, ...). If the
-// source cannot be opened the error is logged and the channel closes empty.
-func Lines(ctx context.Context, path string) <-chan string {
-	out := make(chan string)
+// ReadJSONL streams the JSON Lines records of path, decoding each into a T with
+// encoding/json. path is interpreted as by Lines. Blank lines are skipped. A
+// record that does not decode is skipped and reported as a *SourceError carrying
+// its line number (see WithErrors); the stream continues with the next record.
+func ReadJSONL[T any](ctx context.Context, path string) <-chan T {
+	out := make(chan T)
 	go func() {
 		defer close(out)
-		f, err := uio.Open(path)
-		if err != nil {
-			slog.Error("gojob: cannot open source", slog.String("path", path), slog.String("error", err.Error()))
-			return
+		err := eachLine(ctx, path, func(line []byte, n int64) error {
+			if len(bytes.TrimSpace(line)) == 0 {
+				return nil
+			}
+			var v T
+			if err := json.Unmarshal(line, &v); err != nil {
+				reportErr(ctx, &SourceError{Path: path, Line: n, Err: err})
+				return nil
+			}
+			select {
+			case out <- v:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		var se *SourceError
+		if errors.As(err, &se) && ctx.Err() == nil {
+			reportErr(ctx, se)
 		}
-		defer f.Close()
-		scanner := bufio.NewScanner(f.(io.Reader))
-		for scanner.Scan() {
+	}()
+	return out
+}
+
+// ReadInputs streams the inputs recorded in a JSON Lines file of
+// ProcessInputs results, typically the failures split off by SplitErrors, so
+// they can be retried:
+//
+//	urls := gojob.ReadInputs[string](ctx, "failures.jsonl")
+//
+// Records are read as by ReadJSONL.
+func ReadInputs[In any](ctx context.Context, path string) <-chan In {
+	type record struct {
+		Value struct {
+			Input In `json:"input"`
+		} `json:"value"`
+	}
+	out := make(chan In)
+	go func() {
+		defer close(out)
+		for r := range ReadJSONL[record](ctx, path) {
 			select {
-			case out <- strings.TrimSpace(scanner.Text()):
+			case out <- r.Value.Input:
 			case <-ctx.Done():
 				return
 			}
 		}
-		if err := scanner.Err(); err != nil {
-			slog.Error("gojob: error reading source", slog.String("path", path), slog.String("error", err.Error()))
-		}
 	}()
 	return out
 }
+
+// openSource opens path for reading, as Lines describes. Local files are
+// opened read-only: uio's default mode would create a missing file and yield
+// nothing instead of failing.
+func openSource(path string) (io.ReadCloser, error) {
+	uri := path
+	if u, err := url.Parse(path); err == nil && path != "-" && (u.Scheme == "" || u.Scheme == "file") {
+		if u.RawQuery != "" {
+			uri += "&mode=read"
+		} else {
+			uri += "?mode=read"
+		}
+	}
+	return uio.Open(uri)
+}
+
+// eachLine calls fn with every line of path (including its trailing newline, if
+// any) and its 1-based line number. Unlike a bufio.Scanner it has no line
+// length limit, since a single record may be arbitrarily large. Failures to
+// open or read path are returned as a *SourceError; errors from fn and ctx are
+// returned as they are.
+func eachLine(ctx context.Context, path string, fn func(line []byte, n int64) error) error {
+	f, err := openSource(path)
+	if err != nil {
+		return &SourceError{Path: path, Err: err}
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for n := int64(1); ; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if ferr := fn(line, n); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &SourceError{Path: path, Line: n, Err: err}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gojob_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -64,3 +65,75 @@ func TestLinesMissing(t *testing.T) {
 		t.Errorf("expected 0 lines from an unopenable source, got %d", count)
 	}
 }
+
+func TestReadJSONL(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	type rec struct {
+		URL string `json:"url"`
+	}
+	path := writeFile(t, t.TempDir(), "in.jsonl", `{"url":"a"}`+"\n\n{bad\n"+`{"url":"b"}`)
+	var got []string
+	for r := range gojob.ReadJSONL[rec](ctx, path) {
+		got = append(got, r.URL)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("ReadJSONL: want [a b], got %v", got)
+	}
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Line != 3 || se.Path != path {
+		t.Errorf("expected a SourceError at %s:3, got %v", path, errs.Err())
+	}
+}
+
+func TestReadJSONLMissing(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	for range gojob.ReadJSONL[map[string]any](ctx, "/nonexistent-dir-gojob/missing.jsonl") {
+		t.Error("expected no records")
+	}
+	if errs.Err() == nil {
+		t.Error("expected the open error to be reported")
+	}
+}
+
+func TestLinesMissingReported(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	for range gojob.Lines(ctx, "/nonexistent-dir-gojob/missing.txt") {
+	}
+	var se *gojob.SourceError
+	if !errors.As(errs.Err(), &se) || se.Path != "/nonexistent-dir-gojob/missing.txt" {
+		t.Errorf("expected a SourceError for the missing path, got %v", errs.Err())
+	}
+}
+
+func TestSourcesMissingFile(t *testing.T) {
+	// A missing file in an existing directory must be reported, not created.
+	path := filepath.Join(t.TempDir(), "typo.txt")
+	sources := map[string]func(ctx context.Context){
+		"Lines":       func(ctx context.Context) { drain(gojob.Lines(ctx, path)) },
+		"LinesMany":   func(ctx context.Context) { drain(gojob.LinesMany(ctx, []string{path})) },
+		"ReadJSONL":   func(ctx context.Context) { drain(gojob.ReadJSONL[any](ctx, path)) },
+		"ReadCSV":     func(ctx context.Context) { drain(gojob.ReadCSV[struct{ A string }](ctx, path)) },
+		"file:// URL": func(ctx context.Context) { drain(gojob.Lines(ctx, "file://"+path)) },
+	}
+	for name, read := range sources {
+		ctx, errs := gojob.WithErrors(context.Background())
+		read(ctx)
+		if errs.Err() == nil {
+			t.Errorf("%s: the missing file was not reported", name)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: the missing file was created", name)
+			os.Remove(path)
+		}
+	}
+}
+
+func TestErrorsFrom(t *testing.T) {
+	if e := gojob.ErrorsFrom(context.Background()); e != nil || e.Err() != nil || e.Len() != 0 {
+		t.Errorf("expected no collector, got %v", e)
+	}
+	ctx, errs := gojob.WithErrors(context.Background())
+	if gojob.ErrorsFrom(ctx) != errs {
+		t.Error("ErrorsFrom should return the collector from WithErrors")
+	}
+}
@@ -0,0 +1,38 @@
+package gojob
+
+import "context"
+
+// Partition routes every item of in to exactly one of two streams: matched if
+// pred returns true, rest otherwise. Unlike Tee nothing is duplicated, but as
+// with Tee both outputs apply backpressure, so both must be consumed. The
+// outputs close when the input does or ctx is cancelled.
+func Partition[T any](ctx context.Context, in <-chan T, pred func(T) bool) (matched, rest <-chan T) {
+	yes, no := make(chan T), make(chan T)
+	go func() {
+		defer close(yes)
+		defer close(no)
+		for v := range in {
+			out := no
+			if pred(v) {
+				out = yes
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return yes, no
+}
+
+// SplitErrors partitions results into successes and failures, e.g. to write
+// them to separate sinks so consumers need not filter on the error:
+//
+//	ok, failed := gojob.SplitErrors(ctx, results)
+//	go gojob.WriteJSONL(ctx, failures, failed)
+//	err := gojob.WriteJSONL(ctx, out, ok)
+func SplitErrors[T any](ctx context.Context, in <-chan Result[T]) (ok, failed <-chan Result[T]) {
+	failed, ok = Partition(ctx, in, func(r Result[T]) bool { return r.Err != nil })
+	return ok, failed
+}
@@ -0,0 +1,78 @@
+package gojob_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/WangYihang/gojob"
+)
+
+func TestPartition(t *testing.T) {
+	ctx := context.Background()
+	even, odd := gojob.Partition(ctx, gojob.From(ctx, 1, 2, 3, 4, 5), func(n int) bool { return n%2 == 0 })
+	var gotEven, gotOdd []int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := range even {
+			gotEven = append(gotEven, n)
+		}
+	}()
+	for n := range odd {
+		gotOdd = append(gotOdd, n)
+	}
+	wg.Wait()
+	if !reflect.DeepEqual(gotEven, []int{2, 4}) || !reflect.DeepEqual(gotOdd, []int{1, 3, 5}) {
+		t.Errorf("got even %v, odd %v", gotEven, gotOdd)
+	}
+}
+
+func TestSplitErrorsRetryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fetch := func(ctx context.Context, url string) (int, error) {
+		if len(url) > 5 {
+			return 0, errors.New("too long")
+		}
+		return len(url), nil
+	}
+	results := gojob.ProcessInputs(ctx, gojob.From(ctx, "a", "bb", "cccccc", "dddddddd"), fetch)
+	ok, failed := gojob.SplitErrors(ctx, results)
+
+	out, err := os.Create(filepath.Join(dir, "failures.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- gojob.WriteJSONL(ctx, out, failed) }()
+	n := 0
+	for r := range ok {
+		if r.Err != nil || r.Value.Output != len(r.Value.Input) {
+			t.Errorf("unexpected success %+v", r)
+		}
+		n++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	if n != 2 {
+		t.Errorf("got %d successes, want 2", n)
+	}
+
+	var retry []string
+	for url := range gojob.ReadInputs[string](ctx, out.Name()) {
+		retry = append(retry, url)
+	}
+	sort.Strings(retry)
+	if want := []string{"cccccc", "dddddddd"}; !reflect.DeepEqual(retry, want) {
+		t.Errorf("got retry inputs %v, want %v", retry, want)
+	}
+}
@@ -12,21 +12,30 @@ import (
 // and read it from any number of observers (a progress reporter, a Prometheus
 // pusher, ...). It is safe for concurrent use.
 type Stats struct {
-	total   int64
+	total   func() int64
 	done    atomic.Int64
 	failed  atomic.Int64
 	started time.Time
 	fin     chan struct{}
+	errs    *Errors
+	name    string
+	dups    *atomic.Int64
+	drops   *atomic.Int64
 }
 
 // Snapshot is an immutable view of the counters at a point in time.
-// Total is -1 when the expected total is unknown (see Total).
+// Total is -1 when the expected total is unknown (see WithTotal), and ETA,
+// the projected time remaining at the average rate so far, is -1 when it
+// cannot be projected.
 type Snapshot struct {
-	Total     int64         `json:"total"`
-	Done      int64         `json:"done"`
-	Succeeded int64         `json:"succeeded"`
-	Failed    int64         `json:"failed"`
-	Elapsed   time.Duration `json:"elapsed"`
+	Total      int64         `json:"total"`
+	Done       int64         `json:"done"`
+	Succeeded  int64         `json:"succeeded"`
+	Failed     int64         `json:"failed"`
+	Elapsed    time.Duration `json:"elapsed"`
+	ETA        time.Duration `json:"eta"`
+	Duplicates int64         `json:"duplicates"`
+	Dropped    int64         `json:"dropped"`
 }
 
 // StatsOption configures WithStats.
@@ -34,29 +43,61 @@ type StatsOption func(*Stats)
 
 // WithTotal records the expected number of items so snapshots have a denominator.
 func WithTotal(n int64) StatsOption {
-	return func(s *Stats) { s.total = n }
+	return func(s *Stats) { s.total = func() int64 { return n } }
+}
+
+// WithTotalFunc reads the expected number of items from f at every snapshot,
+// for totals learned while the job runs (see LinesWithTotal). f returns -1
+// while the total is unknown and must be safe for concurrent use.
+func WithTotalFunc(f func() int64) StatsOption {
+	return func(s *Stats) {
+		if f != nil {
+			s.total = f
+		}
+	}
+}
+
+// WithDuplicates reports the duplicates counted in n (see WithDedupCounter)
+// in snapshots.
+func WithDuplicates(n *atomic.Int64) StatsOption {
+	return func(s *Stats) { s.dups = n }
+}
+
+// WithDropped reports the results a Tee branch dropped, counted in n (see
+// WithDropCounter), in snapshots.
+func WithDropped(n *atomic.Int64) StatsOption {
+	return func(s *Stats) { s.drops = n }
+}
+
+// WithStatsName names the WithStats stage in the pipeline's Topology (see
+// WithTopology).
+func WithStatsName(name string) StatsOption {
+	return func(s *Stats) { s.name = name }
 }
 
 // WithStats returns a pass-through of in that counts results as they flow, plus
 // a Stats handle observers can read. The pass-through must be drained (by a
 // sink) for the counts to advance and for the stream to be reported complete.
+// If ctx carries an Errors (see WithErrors), Stats.Err reports its source errors.
 func WithStats[T any](ctx context.Context, in <-chan Result[T], opts ...StatsOption) (<-chan Result[T], *Stats) {
-	s := &Stats{total: -1, started: time.Now(), fin: make(chan struct{})}
+	s := &Stats{total: func() int64 { return -1 }, started: time.Now(), fin: make(chan struct{}), errs: ErrorsFrom(ctx)}
 	for _, o := range opts {
 		o(s)
 	}
+	st := newStage(ctx, "stats", s.name, 0, in)
 	out := make(chan Result[T])
+	p := output(st, out)
 	go func() {
 		defer close(out)
+		defer st.done()
 		defer close(s.fin)
 		for r := range in {
+			st.received()
 			s.done.Add(1)
 			if r.Err != nil {
 				s.failed.Add(1)
 			}
-			select {
-			case out <- r:
-			case <-ctx.Done():
+			if !emitTo(ctx, p, out, r) {
 				return
 			}
 		}
@@ -68,18 +109,45 @@ func WithStats[T any](ctx context.Context, in <-chan Result[T], opts ...StatsOpt
 func (s *Stats) Snapshot() Snapshot {
 	done := s.done.Load()
 	failed := s.failed.Load()
-	return Snapshot{
-		Total:     s.total,
+	total := s.total()
+	elapsed := time.Since(s.started)
+	eta := time.Duration(-1)
+	switch {
+	case total >= 0 && done >= total:
+		eta = 0
+	case total > 0 && done > 0:
+		eta = time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+	}
+	snap := Snapshot{
+		Total:     total,
 		Done:      done,
 		Succeeded: done - failed,
 		Failed:    failed,
-		Elapsed:   time.Since(s.started),
+		Elapsed:   elapsed,
+		ETA:       eta,
+	}
+	if s.dups != nil {
+		snap.Duplicates = s.dups.Load()
 	}
+	if s.drops != nil {
+		snap.Dropped = s.drops.Load()
+	}
+	return snap
 }
 
 // Done is closed once the observed stream has ended.
 func (s *Stats) Done() <-chan struct{} { return s.fin }
 
+// Err returns the errors reported by the sources feeding the observed stream,
+// or nil if there were none or ctx carried no Errors. Once Done is closed it
+// is final: a non-nil Err means the run saw only part of its input.
+func (s *Stats) Err() error {
+	if s.errs == nil {
+		return nil
+	}
+	return s.errs.Err()
+}
+
 // Stream emits a Snapshot every interval until the observed stream ends, then a
 // final Snapshot, then closes. Each call returns an independent channel.
 func (s *Stats) Stream(interval time.Duration) <-chan Snapshot {
@@ -105,12 +173,23 @@ func (s *Stats) Stream(interval time.Duration) <-chan Snapshot {
 // the observed stream ends. Run it in its own goroutine.
 func ReportEvery(stats *Stats, interval time.Duration, w io.Writer) {
 	for snap := range stats.Stream(interval) {
-		if snap.Total >= 0 {
-			fmt.Fprintf(w, "progress: %d/%d done, %d ok, %d failed, elapsed %s\n",
-				snap.Done, snap.Total, snap.Succeeded, snap.Failed, snap.Elapsed.Round(time.Second))
-		} else {
-			fmt.Fprintf(w, "progress: %d done, %d ok, %d failed, elapsed %s\n",
-				snap.Done, snap.Succeeded, snap.Failed, snap.Elapsed.Round(time.Second))
+		dups := ""
+		if snap.Duplicates > 0 {
+			dups = fmt.Sprintf(", %d duplicates skipped", snap.Duplicates)
+		}
+		if snap.Dropped > 0 {
+			dups += fmt.Sprintf(", %d dropped", snap.Dropped)
+		}
+		switch {
+		case snap.Total >= 0 && snap.ETA >= 0:
+			fmt.Fprintf(w, "progress: %d/%d done, %d ok, %d failed, elapsed %s, eta %s%s\n",
+				snap.Done, snap.Total, snap.Succeeded, snap.Failed, snap.Elapsed.Round(time.Second), snap.ETA.Round(time.Second), dups)
+		case snap.Total >= 0:
+			fmt.Fprintf(w, "progress: %d/%d done, %d ok, %d failed, elapsed %s%s\n",
+				snap.Done, snap.Total, snap.Succeeded, snap.Failed, snap.Elapsed.Round(time.Second), dups)
+		default:
+			fmt.Fprintf(w, "progress: %d done, %d ok, %d failed, elapsed %s%s\n",
+				snap.Done, snap.Succeeded, snap.Failed, snap.Elapsed.Round(time.Second), dups)
 		}
 	}
 }
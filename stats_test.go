@@ -3,6 +3,7 @@ package gojob_test
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -75,3 +76,40 @@ func TestStatsStreamFinalizes(t *testing.T) {
 		t.Errorf("expected final snapshot Done=3, got %d", last.Done)
 	}
 }
+
+func TestStatsSourceError(t *testing.T) {
+	ctx, _ := gojob.WithErrors(context.Background())
+	lines := gojob.Lines(ctx, "/nonexistent-dir-gojob/missing.txt")
+	results := gojob.Process(ctx, lines, func(ctx context.Context, s string) (string, error) { return s, nil })
+	results, stats := gojob.WithStats(ctx, results)
+	gojob.Drain(results)
+	<-stats.Done()
+	if stats.Err() == nil {
+		t.Error("expected Stats.Err to report the source error")
+	}
+}
+
+func TestStatsTotalFuncAndETA(t *testing.T) {
+	ctx := context.Background()
+	var total atomic.Int64
+	total.Store(-1)
+	src := make(chan int)
+	results := gojob.Process(ctx, src, func(ctx context.Context, n int) (int, error) { return n, nil })
+	results, stats := gojob.WithStats(ctx, results, gojob.WithTotalFunc(total.Load))
+	if snap := stats.Snapshot(); snap.Total != -1 || snap.ETA != -1 {
+		t.Errorf("expected unknown total and ETA, got %+v", snap)
+	}
+	total.Store(4)
+	src <- 1
+	<-results
+	if snap := stats.Snapshot(); snap.Total != 4 || snap.ETA <= 0 {
+		t.Errorf("expected total 4 and a positive ETA, got %+v", snap)
+	}
+	close(src)
+	for range results {
+	}
+	total.Store(1)
+	if snap := stats.Snapshot(); snap.ETA != 0 {
+		t.Errorf("expected zero ETA once done, got %v", snap.ETA)
+	}
+}
@@ -20,18 +20,27 @@ type TaskFunc[T any] func(ctx context.Context) (T, error)
 func (f TaskFunc[T]) Execute(ctx context.Context) (T, error) { return f(ctx) }
 
 // Result is the outcome of processing a single item, carrying the produced
-// value alongside its error and execution metadata.
+// value alongside its error and execution metadata. Cached is set when the
+// outcome came from a cache (see WithCache) rather than from running the item.
 type Result[T any] struct {
 	Value     T
 	Err       error
 	Attempts  int
 	StartedAt time.Time
 	Duration  time.Duration
+	Cached    bool
+}
+
+// Item pairs an input with the output Process produced from it; see
+// ProcessInputs.
+type Item[In, Out any] struct {
+	Input  In  `json:"input"`
+	Output Out `json:"output"`
 }
 
 // MarshalJSON renders a Result as a flat, log-friendly JSON object. The error
 // is emitted as a string ("" when there was none), so results serialize cleanly
-// to JSON Lines.
+// to JSON Lines; "cached" appears only on cache hits.
 func (r Result[T]) MarshalJSON() ([]byte, error) {
 	var errStr string
 	if r.Err != nil {
@@ -43,11 +52,13 @@ func (r Result[T]) MarshalJSON() ([]byte, error) {
 		Attempts   int    `json:"attempts"`
 		StartedAt  int64  `json:"started_at"`
 		DurationMs int64  `json:"duration_ms"`
+		Cached     bool   `json:"cached,omitempty"`
 	}{
 		Value:      r.Value,
 		Error:      errStr,
 		Attempts:   r.Attempts,
 		StartedAt:  r.StartedAt.UnixMicro(),
 		DurationMs: r.Duration.Milliseconds(),
+		Cached:     r.Cached,
 	})
 }
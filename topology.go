@@ -0,0 +1,321 @@
+package gojob
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topology records the stages of a pipeline — Process, ProcessInputs, Then,
+// Shard, Tee and WithStats — and how they are connected, with live counters
+// for each, so a run can show where items back up. Obtain one from
+// WithTopology; it is safe for concurrent use.
+type Topology struct {
+	mu     sync.Mutex
+	stages []*stage
+	ports  map[any]*port // open output channels, as <-chan T, by channel
+	taken  map[string]bool
+}
+
+type topologyKey struct{}
+
+// WithTopology returns a copy of parent that stages register with, and the
+// Topology that records them. Stages are named after their kind ("process-1",
+// "tee-1", ...) unless given a name with WithName, WithTeeName or
+// WithStatsName, and one stage feeding another directly is recorded as an
+// edge between them; stages fed through anything else (a source, a
+// combinator), or by a stage that had already finished, have no recorded
+// input.
+//
+//	ctx, topo := gojob.WithTopology(ctx)
+//	pages := gojob.Process(ctx, urls, fetch, gojob.WithName("fetch"), gojob.WithWorkers(64))
+//	docs := gojob.Then(ctx, pages, parse, gojob.WithName("parse"))
+//	...
+//	fmt.Println(topo.Snapshot().DOT())
+func WithTopology(parent context.Context) (context.Context, *Topology) {
+	t := &Topology{ports: map[any]*port{}, taken: map[string]bool{}}
+	return context.WithValue(parent, topologyKey{}, t), t
+}
+
+// TopologyFrom returns the Topology installed in ctx by WithTopology, or nil.
+func TopologyFrom(ctx context.Context) *Topology {
+	t, _ := ctx.Value(topologyKey{}).(*Topology)
+	return t
+}
+
+// StageSnapshot is a view of one stage's counters. Queued counts the items
+// waiting to enter the stage: those buffered on its input, those its upstream
+// stage is blocked sending to it, and those it has taken but not started, such
+// as items in a WithPriority window or waiting for a Resource. Blocked counts
+// the items the stage itself is blocked handing downstream; a large Blocked on
+// a stage whose consumer is not recorded (such as a sink) means that consumer
+// is the bottleneck. Out counts the items the stage passed on; for a Tee, a
+// result counts once every branch has been offered it, and Outputs holds the
+// number each branch actually received. Dropped counts the results a Tee's
+// lossy branches discarded (see WithDropPolicy). Throughput is the stage's
+// average output rate, in items per second, since it started.
+type StageSnapshot struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Inputs     []string `json:"inputs,omitempty"`
+	Workers    int      `json:"workers,omitempty"`
+	In         int64    `json:"in"`
+	Out        int64    `json:"out"`
+	Outputs    []int64  `json:"outputs,omitempty"`
+	Dropped    int64    `json:"dropped,omitempty"`
+	InFlight   int64    `json:"in_flight"`
+	Queued     int64    `json:"queued"`
+	Blocked    int64    `json:"blocked"`
+	Throughput float64  `json:"throughput"`
+}
+
+// TopologySnapshot is a view of all stages, in the order they were created
+// (upstream before downstream). Bottleneck names the stage with the most items
+// queued on its input, or is empty if nothing is queued.
+type TopologySnapshot struct {
+	Stages     []StageSnapshot `json:"stages"`
+	Bottleneck string          `json:"bottleneck,omitempty"`
+}
+
+// Snapshot returns the current counters of every stage; marshal it with
+// encoding/json for the JSON form, or call DOT.
+func (t *Topology) Snapshot() TopologySnapshot {
+	if t == nil {
+		return TopologySnapshot{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snap := TopologySnapshot{Stages: make([]StageSnapshot, len(t.stages))}
+	var most int64
+	for i, s := range t.stages {
+		ss := StageSnapshot{
+			Name:     s.name,
+			Kind:     s.kind,
+			Workers:  s.workers,
+			In:       s.in.Load(),
+			InFlight: s.inFlight.Load(),
+			Queued:   s.held.Load(),
+		}
+		for _, in := range s.inputs {
+			ss.Queued += int64(in.queued())
+			if p := in.from; p != nil {
+				ss.Inputs = append(ss.Inputs, p.stage.name)
+				ss.Queued += p.waiting.Load()
+			}
+		}
+		for _, p := range s.ports {
+			ss.Blocked += p.waiting.Load()
+			ss.Dropped += p.dropped.Load()
+		}
+		if len(s.ports) == 1 {
+			ss.Out = s.ports[0].count.Load()
+		} else {
+			ss.Out = s.out.Load()
+			for _, p := range s.ports {
+				ss.Outputs = append(ss.Outputs, p.count.Load())
+			}
+		}
+		if elapsed := time.Since(s.started).Seconds(); elapsed > 0 {
+			ss.Throughput = float64(ss.Out) / elapsed
+		}
+		if ss.Queued > most {
+			most, snap.Bottleneck = ss.Queued, ss.Name
+		}
+		snap.Stages[i] = ss
+	}
+	return snap
+}
+
+// DOT renders the snapshot as a Graphviz graph, one node per stage with its
+// counters, the bottleneck highlighted:
+//
+//	dot -Tsvg topology.dot > topology.svg
+func (s TopologySnapshot) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n\trankdir=LR;\n\tnode [shape=box, style=rounded];\n")
+	for _, st := range s.Stages {
+		kind := st.Kind
+		if st.Workers > 0 {
+			kind = fmt.Sprintf("%s ×%d", kind, st.Workers)
+		}
+		label := fmt.Sprintf("%s (%s)\n%d in flight, %d queued, %d blocked\n%d out, %.1f/s",
+			st.Name, kind, st.InFlight, st.Queued, st.Blocked, st.Out, st.Throughput)
+		if st.Dropped > 0 {
+			label += fmt.Sprintf(", %d dropped", st.Dropped)
+		}
+		attrs := ""
+		if st.Name == s.Bottleneck {
+			attrs = ", color=red, penwidth=2"
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s%s];\n", dotQuote(st.Name), dotQuote(label), attrs)
+	}
+	for _, st := range s.Stages {
+		for _, in := range st.Inputs {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(in), dotQuote(st.Name))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes s as a DOT string, turning newlines into line breaks.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// stage is the record of one pipeline stage. A nil *stage (no Topology in
+// ctx) records nothing, so stages call its methods unconditionally.
+type stage struct {
+	topo     *Topology
+	name     string
+	kind     string
+	workers  int
+	started  time.Time
+	inputs   []stageInput
+	ports    []*port
+	in       atomic.Int64
+	out      atomic.Int64 // items passed on, for stages with several outputs
+	inFlight atomic.Int64
+	held     atomic.Int64 // taken from the input but not started yet
+}
+
+type stageInput struct {
+	from   *port      // nil unless fed by another stage
+	queued func() int // items buffered on the channel
+}
+
+// port is an output channel of a stage.
+type port struct {
+	stage   *stage
+	key     any          // the channel, as the receiving stage sees it
+	count   atomic.Int64 // items sent on it
+	dropped atomic.Int64 // items discarded instead of sent
+	waiting atomic.Int64 // items blocked being sent on it
+}
+
+// newStage registers a stage reading from in with the Topology in ctx, if
+// any. An empty name is replaced by one derived from kind; a name already
+// taken gets a numeric suffix.
+func newStage[T any](ctx context.Context, kind, name string, workers int, in <-chan T) *stage {
+	t := TopologyFrom(ctx)
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if name == "" || t.taken[name] {
+		base, i := name, 2
+		if name == "" {
+			base, i = kind, 1
+		}
+		for name = fmt.Sprintf("%s-%d", base, i); t.taken[name]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i+1)
+		}
+	}
+	t.taken[name] = true
+	s := &stage{
+		topo:    t,
+		name:    name,
+		kind:    kind,
+		workers: workers,
+		started: time.Now(),
+		inputs:  []stageInput{{from: t.ports[in], queued: func() int { return len(in) }}},
+	}
+	t.stages = append(t.stages, s)
+	return s
+}
+
+// output registers ch as an output of the stage.
+func output[T any](s *stage, ch chan T) *port {
+	if s == nil {
+		return nil
+	}
+	p := &port{stage: s, key: (<-chan T)(ch)}
+	s.topo.mu.Lock()
+	defer s.topo.mu.Unlock()
+	s.ports = append(s.ports, p)
+	s.topo.ports[p.key] = p
+	return p
+}
+
+// done unregisters the stage's outputs once it has finished sending on them,
+// so the Topology does not hold on to closed channels. The stage's counters
+// and recorded edges remain.
+func (s *stage) done() {
+	if s == nil {
+		return
+	}
+	s.topo.mu.Lock()
+	defer s.topo.mu.Unlock()
+	for _, p := range s.ports {
+		delete(s.topo.ports, p.key)
+	}
+}
+
+// received counts an item taken from the stage's input.
+func (s *stage) received() {
+	if s != nil {
+		s.in.Add(1)
+	}
+}
+
+// hold counts n more items taken from the input that wait inside the stage
+// before they start, e.g. for a resource.
+func (s *stage) hold(n int64) {
+	if s != nil {
+		s.held.Add(n)
+	}
+}
+
+// passed counts an item handed to all of the stage's outputs, for stages with
+// more than one.
+func (s *stage) passed() {
+	if s != nil {
+		s.out.Add(1)
+	}
+}
+
+// begin and end bracket the processing of an item.
+func (s *stage) begin() {
+	if s != nil {
+		s.inFlight.Add(1)
+	}
+}
+
+func (s *stage) end() {
+	if s != nil {
+		s.inFlight.Add(-1)
+	}
+}
+
+// emitTo sends v on out unless ctx is cancelled first, counting v as waiting
+// on p while the send blocks.
+func emitTo[T any](ctx context.Context, p *port, out chan<- T, v T) bool {
+	if p == nil {
+		return send(ctx, out, v)
+	}
+	p.waiting.Add(1)
+	ok := send(ctx, out, v)
+	p.waiting.Add(-1)
+	if ok {
+		p.count.Add(1)
+	}
+	return ok
+}
+
+// sent counts an item handed downstream without blocking.
+func (p *port) sent() {
+	if p != nil {
+		p.count.Add(1)
+	}
+}
+
+// drop counts an item discarded instead of handed downstream.
+func (p *port) drop() {
+	if p != nil {
+		p.dropped.Add(1)
+	}
+}
@@ -0,0 +1,162 @@
+package gojob_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func stageByName(snap gojob.TopologySnapshot, name string) (gojob.StageSnapshot, bool) {
+	for _, s := range snap.Stages {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return gojob.StageSnapshot{}, false
+}
+
+func TestTopologyBottleneck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, topo := gojob.WithTopology(ctx)
+
+	release := make(chan struct{})
+	fetched := gojob.Process(ctx, gojob.From(ctx, rangeInts(20)...), func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}, gojob.WithName("fetch"), gojob.WithWorkers(4))
+	parsed := gojob.Then(ctx, fetched, func(ctx context.Context, n int) (int, error) {
+		<-release
+		return n, nil
+	}, gojob.WithName("parse"))
+	results, _ := gojob.WithStats(ctx, parsed)
+	done := make(chan []gojob.Result[int])
+	go func() { done <- collect(results) }()
+
+	// parse holds one item while fetch's four workers wait to hand it theirs.
+	waitFor(t, func() bool {
+		s, _ := stageByName(topo.Snapshot(), "parse")
+		return s.InFlight == 1 && s.Queued == 4
+	})
+	snap := topo.Snapshot()
+	if snap.Bottleneck != "parse" {
+		t.Errorf("bottleneck = %q, want parse", snap.Bottleneck)
+	}
+	if f, _ := stageByName(snap, "fetch"); f.Blocked != 4 || f.Workers != 4 || f.Kind != "process" {
+		t.Errorf("fetch = %+v", f)
+	}
+
+	close(release)
+	if got := len(<-done); got != 20 {
+		t.Fatalf("got %d results, want 20", got)
+	}
+	snap = topo.Snapshot()
+	var names []string
+	for _, s := range snap.Stages {
+		names = append(names, s.Name)
+		if s.In != 20 || s.Out != 20 || s.InFlight != 0 || s.Queued != 0 {
+			t.Errorf("%s = %+v after the run", s.Name, s)
+		}
+	}
+	if got := strings.Join(names, ","); got != "fetch,parse,stats-1" {
+		t.Errorf("stages = %s", got)
+	}
+	if s, _ := stageByName(snap, "stats-1"); len(s.Inputs) != 1 || s.Inputs[0] != "parse" {
+		t.Errorf("stats inputs = %v, want [parse]", s.Inputs)
+	}
+	if s, _ := stageByName(snap, "fetch"); len(s.Inputs) != 0 {
+		t.Errorf("fetch is fed by a source, got inputs %v", s.Inputs)
+	}
+}
+
+func TestTopologyDOTAndJSON(t *testing.T) {
+	ctx, topo := gojob.WithTopology(context.Background())
+	results := gojob.Process(ctx, gojob.From(ctx, 1, 2, 3), func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}, gojob.WithName(`a "quoted" name`))
+	outs := gojob.Tee(ctx, results, 2, gojob.WithTeeName("fanout"))
+	go gojob.Drain(outs[1])
+	sharded := gojob.Shard(ctx, outs[0], 2, 0)
+	gojob.Drain(sharded)
+
+	snap := topo.Snapshot()
+	dot := snap.DOT()
+	for _, want := range []string{
+		`digraph pipeline {`,
+		`"a \"quoted\" name" -> "fanout";`,
+		`"fanout" -> "shard-1";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT lacks %s:\n%s", want, dot)
+		}
+	}
+	if s, _ := stageByName(snap, "fanout"); s.In != 3 || s.Out != 3 || len(s.Outputs) != 2 || s.Outputs[0] != 3 {
+		t.Errorf("tee = %+v, want 3 in, 3 out, 3 on the first branch", s)
+	}
+	if s, _ := stageByName(snap, "shard-1"); s.In != 3 || s.Out != 2 {
+		t.Errorf("shard = %+v, want 3 in, 2 out", s)
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back gojob.TopologySnapshot
+	if err := json.Unmarshal(b, &back); err != nil || len(back.Stages) != 3 || back.Stages[1].Inputs[0] != `a "quoted" name` {
+		t.Errorf("JSON round trip: %s (%v)", b, err)
+	}
+
+	// A finished stage's output is no longer tracked.
+	gojob.Drain(gojob.Shard(ctx, sharded, 2, 0, gojob.WithName("late")))
+	if s, _ := stageByName(topo.Snapshot(), "late"); len(s.Inputs) != 0 {
+		t.Errorf("late stage inputs = %v, want none", s.Inputs)
+	}
+}
+
+func TestTopologyNames(t *testing.T) {
+	ctx, topo := gojob.WithTopology(context.Background())
+	id := func(ctx context.Context, n int) (int, error) { return n, nil }
+	gojob.Drain(gojob.Process(ctx, gojob.From(ctx, 1), id, gojob.WithName("x")))
+	gojob.Drain(gojob.Process(ctx, gojob.From(ctx, 1), id, gojob.WithName("x")))
+	gojob.Drain(gojob.Process(ctx, gojob.From(ctx, 1), id))
+	var names []string
+	for _, s := range topo.Snapshot().Stages {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "x,x-2,process-1" {
+		t.Errorf("names = %s", got)
+	}
+	if gojob.TopologyFrom(context.Background()).Snapshot().Stages != nil {
+		t.Error("a nil Topology should have no stages")
+	}
+}
+
+func TestTopologyTeeDrops(t *testing.T) {
+	ctx, topo := gojob.WithTopology(context.Background())
+	outs := gojob.Tee(ctx, intResults(ctx, 100), 2, gojob.WithTeeName("fanout"),
+		gojob.WithBranch(1, gojob.WithBuffer(10), gojob.WithDropPolicy(gojob.DropNewest)))
+	gojob.Drain(outs[0])
+	gojob.Drain(outs[1])
+	s, _ := stageByName(topo.Snapshot(), "fanout")
+	if s.Dropped != 90 || len(s.Outputs) != 2 || s.Outputs[1] != 10 {
+		t.Errorf("tee = %+v, want 90 dropped, 10 on the lossy branch", s)
+	}
+	if dot := topo.Snapshot().DOT(); !strings.Contains(dot, "90 dropped") {
+		t.Errorf("DOT lacks the drops:\n%s", dot)
+	}
+}
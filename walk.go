@@ -0,0 +1,170 @@
+package gojob
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+type walkConfig struct {
+	globs    []string
+	maxDepth int
+	follow   bool
+}
+
+// WalkOption configures WalkFiles and WalkFS.
+type WalkOption func(*walkConfig)
+
+// WithGlob keeps only files whose base name matches pattern (see path.Match),
+// like find -name. Given several times, a file matching any pattern is kept.
+func WithGlob(pattern string) WalkOption {
+	return func(c *walkConfig) {
+		c.globs = append(c.globs, pattern)
+	}
+}
+
+// WithMaxDepth limits how deep the walk descends: 1 yields only the files
+// directly under the root, like find -maxdepth. Non-positive values mean no
+// limit (the default).
+func WithMaxDepth(n int) WalkOption {
+	return func(c *walkConfig) {
+		c.maxDepth = n
+	}
+}
+
+// WithFollowSymlinks makes the walk resolve symbolic links, descending into
+// linked directories (each directory at most once along any path, so link
+// cycles terminate) and yielding linked files as the files they point to. By
+// default links are yielded as they are, without being resolved.
+func WithFollowSymlinks(follow bool) WalkOption {
+	return func(c *walkConfig) {
+		c.follow = follow
+	}
+}
+
+// File is a file found by WalkFiles or WalkFS.
+type File struct {
+	// Path locates the file: an OS path for WalkFiles, a slash-separated path
+	// within the file system for WalkFS.
+	Path  string      `json:"path"`
+	Entry fs.DirEntry `json:"-"`
+
+	fsys fs.FS
+	name string
+}
+
+// Open opens the file for reading.
+func (f File) Open() (fs.File, error) { return f.fsys.Open(f.name) }
+
+// Info returns the file's metadata, resolved through the link if the walk
+// followed symlinks.
+func (f File) Info() (fs.FileInfo, error) { return f.Entry.Info() }
+
+// WalkFiles streams every file (anything but a directory) in the tree under
+// the local directory root, depth first in lexical order, without reading the
+// whole tree up front. Unlike piping find into Lines, file names may contain
+// any character and each item keeps its fs.DirEntry. A directory that cannot be
+// read is reported as a *SourceError (see WithErrors) and skipped.
+func WalkFiles(ctx context.Context, root string, opts ...WalkOption) <-chan File {
+	return walk(ctx, os.DirFS(root), ".", func(name string) string {
+		return filepath.Join(root, filepath.FromSlash(name))
+	}, opts)
+}
+
+// WalkFS is WalkFiles over any fs.FS, such as an embed.FS or an fstest.MapFS,
+// starting at root ("." for the whole file system).
+func WalkFS(ctx context.Context, fsys fs.FS, root string, opts ...WalkOption) <-chan File {
+	return walk(ctx, fsys, root, func(name string) string { return name }, opts)
+}
+
+func walk(ctx context.Context, fsys fs.FS, root string, display func(string) string, opts []WalkOption) <-chan File {
+	var cfg walkConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	out := make(chan File)
+	go func() {
+		defer close(out)
+		for _, g := range cfg.globs {
+			if _, err := path.Match(g, ""); err != nil {
+				reportErr(ctx, &SourceError{Path: g, Err: err})
+				return
+			}
+		}
+		w := &walker{ctx: ctx, fsys: fsys, cfg: cfg, display: display, out: out}
+		w.dir(root, 1, nil)
+	}()
+	return out
+}
+
+type walker struct {
+	ctx     context.Context
+	fsys    fs.FS
+	cfg     walkConfig
+	display func(string) string
+	out     chan<- File
+}
+
+// dir walks the directory name, whose entries are at the given depth.
+// ancestors holds the directories on the current path when following links,
+// to stop at cycles. It returns false once ctx is cancelled.
+func (w *walker) dir(name string, depth int, ancestors []fs.FileInfo) bool {
+	entries, err := fs.ReadDir(w.fsys, name)
+	if err != nil {
+		reportErr(w.ctx, &SourceError{Path: w.display(name), Err: err})
+		return w.ctx.Err() == nil
+	}
+	if w.cfg.follow {
+		if info, err := fs.Stat(w.fsys, name); err == nil {
+			for _, a := range ancestors {
+				if os.SameFile(a, info) {
+					return true // a link back to a directory being walked
+				}
+			}
+			ancestors = append(ancestors, info)
+		}
+	}
+	for _, e := range entries {
+		child := path.Join(name, e.Name())
+		if w.cfg.follow && e.Type()&fs.ModeSymlink != 0 {
+			info, err := fs.Stat(w.fsys, child)
+			if err != nil {
+				reportErr(w.ctx, &SourceError{Path: w.display(child), Err: err})
+				continue
+			}
+			e = fs.FileInfoToDirEntry(info)
+		}
+		if e.IsDir() {
+			if w.cfg.maxDepth > 0 && depth >= w.cfg.maxDepth {
+				continue
+			}
+			if !w.dir(child, depth+1, ancestors) {
+				return false
+			}
+			continue
+		}
+		if !w.match(e.Name()) {
+			continue
+		}
+		select {
+		case w.out <- File{Path: w.display(child), Entry: e, fsys: w.fsys, name: child}:
+		case <-w.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (w *walker) match(name string) bool {
+	if len(w.cfg.globs) == 0 {
+		return true
+	}
+	for _, g := range w.cfg.globs {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
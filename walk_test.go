@@ -0,0 +1,98 @@
+package gojob_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/WangYihang/gojob"
+)
+
+// walkPaths collects the paths a walk yields.
+func walkPaths(files <-chan gojob.File) []string {
+	var got []string
+	for f := range files {
+		got = append(got, f.Path)
+	}
+	return got
+}
+
+func TestWalkFS(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a.pdf":           {Data: []byte("a")},
+		"b.txt":           {Data: []byte("b")},
+		"docs/c.pdf":      {Data: []byte("c")},
+		"docs/deep/d.pdf": {Data: []byte("d")},
+		"docs/line\n.pdf": {Data: []byte("e")},
+	}
+
+	got := walkPaths(gojob.WalkFS(ctx, fsys, ".", gojob.WithGlob("*.pdf")))
+	want := []string{"a.pdf", "docs/c.pdf", "docs/deep/d.pdf", "docs/line\n.pdf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	got = walkPaths(gojob.WalkFS(ctx, fsys, "docs", gojob.WithMaxDepth(1)))
+	want = []string{"docs/c.pdf", "docs/line\n.pdf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("depth 1: want %q, got %q", want, got)
+	}
+
+	for f := range gojob.WalkFS(ctx, fsys, "docs/deep") {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(r)
+		r.Close()
+		if string(b) != "d" {
+			t.Errorf("Open: want %q, got %q", "d", b)
+		}
+	}
+}
+
+func TestWalkFilesSymlinks(t *testing.T) {
+	ctx, errs := gojob.WithErrors(context.Background())
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "a")
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "sub"), "b.txt", "b")
+	for link, target := range map[string]string{
+		"sub/loop":   "..",    // a cycle back to root
+		"link.txt":   "a.txt", // a link to a file
+		"linked-dir": "sub",   // a link to a directory
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	got := walkPaths(gojob.WalkFiles(ctx, root))
+	want := []string{"a.txt", "link.txt", "linked-dir", "sub/b.txt", "sub/loop"}
+	for i := range want {
+		want[i] = filepath.Join(root, want[i])
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("without following: want %q, got %q", want, got)
+	}
+
+	got = walkPaths(gojob.WalkFiles(ctx, root, gojob.WithFollowSymlinks(true)))
+	// Both loop links lead back to root, which is already being walked.
+	want = []string{"a.txt", "link.txt", "linked-dir/b.txt", "sub/b.txt"}
+	for i := range want {
+		want[i] = filepath.Join(root, want[i])
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("following: want %q, got %q", want, got)
+	}
+	if err := errs.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
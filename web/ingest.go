@@ -0,0 +1,403 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+type ingestConfig struct {
+	path    string
+	queue   int
+	block   bool
+	maxBody int64
+	timeout time.Duration
+	process []gojob.Option
+}
+
+// IngestOption configures Ingest and IngestProcess.
+type IngestOption func(*ingestConfig)
+
+// WithPath sets the path items are POSTed to (default "/items").
+func WithPath(path string) IngestOption {
+	return func(c *ingestConfig) {
+		if path != "" {
+			c.path = path
+		}
+	}
+}
+
+// WithQueue lets up to n accepted items wait for the pipeline (default 0:
+// an item is only accepted when a worker is ready to take it).
+func WithQueue(n int) IngestOption {
+	return func(c *ingestConfig) {
+		if n > 0 {
+			c.queue = n
+		}
+	}
+}
+
+// WithBlocking makes a request wait for the pipeline to accept its items
+// instead of being answered 429 Too Many Requests when it is saturated.
+func WithBlocking() IngestOption {
+	return func(c *ingestConfig) {
+		c.block = true
+	}
+}
+
+// WithMaxBodySize limits the size of a request body in bytes (default 10 MiB).
+func WithMaxBodySize(n int64) IngestOption {
+	return func(c *ingestConfig) {
+		if n > 0 {
+			c.maxBody = n
+		}
+	}
+}
+
+// WithProcess sets the gojob.Process options (workers, retries) IngestProcess
+// runs with. Use WithTimeout rather than gojob.WithTimeout for a per-attempt
+// timeout, so that a timed-out item can still be answered.
+func WithProcess(opts ...gojob.Option) IngestOption {
+	return func(c *ingestConfig) {
+		c.process = append(c.process, opts...)
+	}
+}
+
+// WithTimeout bounds a single attempt in IngestProcess; zero (the default)
+// means no timeout.
+func WithTimeout(d time.Duration) IngestOption {
+	return func(c *ingestConfig) {
+		c.timeout = d
+	}
+}
+
+func ingestDefaults(opts []IngestOption) ingestConfig {
+	cfg := ingestConfig{path: "/items", maxBody: 10 << 20}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// Ingest registers a POST handler on mux and streams the items clients send,
+// turning a running pipeline into a service that accepts work over HTTP:
+//
+//	mux := http.NewServeMux()
+//	jobs := web.Ingest[Job](ctx, mux)
+//	results := gojob.Process(ctx, jobs, handle, gojob.WithWorkers(8))
+//	go http.ListenAndServe(":8080", mux)
+//
+// A request body holds either a JSON array of items or a stream of JSON values
+// (JSON Lines). Items are accepted in order; the response is 202 Accepted with
+// {"accepted": n}. When the pipeline cannot take an item right away the
+// request is answered 429 Too Many Requests (with Retry-After) unless
+// WithBlocking is set; a malformed item gets 400 Bad Request. In both cases
+// the items before it have been accepted and "accepted" counts them, so a
+// client can resend the rest.
+//
+// The stream closes once ctx is cancelled, after any items still queued (see
+// WithQueue); requests arriving after that are answered 503 Service
+// Unavailable.
+func Ingest[T any](ctx context.Context, mux *http.ServeMux, opts ...IngestOption) <-chan T {
+	cfg := ingestDefaults(opts)
+	items := make(chan T, cfg.queue)
+	g := &ingester[T]{ctx: ctx, cfg: cfg, items: items,
+		decode: func(raw json.RawMessage, _ bool) (T, func(context.Context) any, error) {
+			var v T
+			err := json.Unmarshal(raw, &v)
+			return v, nil, err
+		},
+	}
+	mux.Handle(cfg.path, g)
+	context.AfterFunc(ctx, g.close)
+	return items
+}
+
+// IngestProcess is Ingest wired into gojob.Process: it runs fn on every item
+// clients send and streams the Results like Process does. Additionally, a
+// client that POSTs with ?wait=true is answered 200 OK only once its items are
+// processed, with a JSON array of their Results in request order — handy for
+// small request/response use cases.
+func IngestProcess[In, Out any](ctx context.Context, mux *http.ServeMux, fn func(context.Context, In) (Out, error), opts ...IngestOption) <-chan gojob.Result[Out] {
+	cfg := ingestDefaults(opts)
+	tickets := make(chan *ticket[In, Out], cfg.queue)
+	g := &ingester[*ticket[In, Out]]{ctx: ctx, cfg: cfg, items: tickets, sync: true,
+		decode: func(raw json.RawMessage, wait bool) (*ticket[In, Out], func(context.Context) any, error) {
+			t := &ticket[In, Out]{}
+			if err := json.Unmarshal(raw, &t.v); err != nil {
+				return nil, nil, err
+			}
+			if !wait {
+				return t, nil, nil
+			}
+			t.reply = make(chan gojob.Result[Out], 1)
+			return t, func(reqCtx context.Context) any {
+				select {
+				case res := <-t.reply:
+					return res
+				case <-ctx.Done():
+					return nil
+				case <-reqCtx.Done():
+					return nil
+				}
+			}, nil
+		},
+	}
+	mux.Handle(cfg.path, g)
+	context.AfterFunc(ctx, g.close)
+
+	type outcome struct {
+		t   *ticket[In, Out]
+		out Out
+	}
+	// As in queue.Consume, the timeout is applied inside work so the ticket
+	// rides through Process even when an attempt times out.
+	work := func(ctx context.Context, t *ticket[In, Out]) (outcome, error) {
+		out, err := runWithTimeout(ctx, cfg.timeout, func(ctx context.Context) (Out, error) {
+			return fn(ctx, t.v)
+		})
+		return outcome{t: t, out: out}, err
+	}
+	processed := gojob.Process(ctx, tickets, work, cfg.process...)
+
+	out := make(chan gojob.Result[Out])
+	go func() {
+		defer close(out)
+		for r := range processed {
+			res := gojob.Result[Out]{
+				Value:     r.Value.out,
+				Err:       r.Err,
+				Attempts:  r.Attempts,
+				StartedAt: r.StartedAt,
+				Duration:  r.Duration,
+			}
+			if t := r.Value.t; t != nil && t.reply != nil {
+				t.reply <- res // buffered: never blocks
+			}
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ticket carries an item through IngestProcess together with where to send its
+// Result when the client is waiting for it.
+type ticket[In, Out any] struct {
+	v     In
+	reply chan gojob.Result[Out]
+}
+
+// ingester is the POST handler shared by Ingest and IngestProcess. decode turns
+// one JSON value into an item; when the client waits (only possible if sync is
+// set) it also returns a func that blocks for the item's Result and returns it,
+// or nil once the pipeline stops or the request is abandoned.
+type ingester[T any] struct {
+	ctx    context.Context
+	cfg    ingestConfig
+	mu     sync.RWMutex // read-held while sending on items, so close waits
+	items  chan T
+	sync   bool
+	decode func(raw json.RawMessage, wait bool) (T, func(context.Context) any, error)
+}
+
+// close closes items, once ctx is cancelled, as soon as no handler is sending
+// on it.
+func (g *ingester[T]) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	close(g.items)
+}
+
+type ingestResponse struct {
+	Accepted int    `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (g *ingester[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if g.ctx.Err() != nil {
+		writeJSON(w, http.StatusServiceUnavailable, ingestResponse{Error: "pipeline stopped"})
+		return
+	}
+	wait := r.URL.Query().Get("wait") == "true"
+	if wait && !g.sync {
+		writeJSON(w, http.StatusBadRequest, ingestResponse{Error: "wait is only supported by IngestProcess"})
+		return
+	}
+
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, g.cfg.maxBody))
+	accepted := 0
+	var replies []func(context.Context) any
+	err := decodeItems(body, func(raw json.RawMessage) error {
+		item, reply, err := g.decode(raw, wait)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", accepted+1, err)
+		}
+		if err := g.send(r.Context(), item); err != nil {
+			return err
+		}
+		accepted++
+		if reply != nil {
+			replies = append(replies, reply)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSaturated):
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusTooManyRequests, ingestResponse{Accepted: accepted, Error: err.Error()})
+		return
+	case errors.Is(err, errStopped):
+		writeJSON(w, http.StatusServiceUnavailable, ingestResponse{Accepted: accepted, Error: err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, ingestResponse{Accepted: accepted, Error: err.Error()})
+		return
+	}
+	if !wait {
+		writeJSON(w, http.StatusAccepted, ingestResponse{Accepted: accepted})
+		return
+	}
+	results := make([]any, 0, len(replies))
+	for _, reply := range replies {
+		res := reply(r.Context())
+		if res == nil {
+			writeJSON(w, http.StatusServiceUnavailable, ingestResponse{Accepted: accepted, Error: errStopped.Error()})
+			return
+		}
+		results = append(results, res)
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+var (
+	errSaturated = errors.New("pipeline saturated")
+	errStopped   = errors.New("pipeline stopped")
+)
+
+// send hands an item to the pipeline, or fails if it is saturated (unless
+// blocking) or stopped.
+func (g *ingester[T]) send(reqCtx context.Context, item T) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.ctx.Err() != nil {
+		return errStopped
+	}
+	if !g.cfg.block {
+		select {
+		case g.items <- item:
+			return nil
+		case <-g.ctx.Done():
+			return errStopped
+		default:
+			return errSaturated
+		}
+	}
+	select {
+	case g.items <- item:
+		return nil
+	case <-g.ctx.Done():
+		return errStopped
+	case <-reqCtx.Done():
+		return reqCtx.Err()
+	}
+}
+
+// decodeItems calls fn with each item of a body holding a JSON array of items
+// or a stream of JSON values.
+func decodeItems(body *bufio.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(body)
+	if isArray(body) {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			if err := fn(raw); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// isArray reports whether the first non-space byte of body opens an array.
+func isArray(body *bufio.Reader) bool {
+	for {
+		b, err := body.Peek(1)
+		if err != nil {
+			return false
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0] == '['
+		}
+		_, _ = body.Discard(1)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// runWithTimeout runs f with a per-attempt timeout, returning the zero value and
+// the context error if it elapses. It mirrors the core engine's behaviour but
+// lives here so IngestProcess can apply the timeout itself and keep the ticket.
+func runWithTimeout[Out any](ctx context.Context, timeout time.Duration, f func(context.Context) (Out, error)) (Out, error) {
+	if timeout <= 0 {
+		return f(ctx)
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type res struct {
+		v   Out
+		err error
+	}
+	done := make(chan res, 1)
+	go func() {
+		v, err := f(cctx)
+		done <- res{v, err}
+	}()
+	select {
+	case <-cctx.Done():
+		var zero Out
+		return zero, cctx.Err()
+	case r := <-done:
+		return r.v, r.err
+	}
+}
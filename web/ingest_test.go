@@ -0,0 +1,136 @@
+package web_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+	"github.com/WangYihang/gojob/web"
+)
+
+type job struct {
+	N int `json:"n"`
+}
+
+func post(t *testing.T, url, body string) (int, map[string]any) {
+	t.Helper()
+	resp, err := noProxy().Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var m map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&m)
+	return resp.StatusCode, m
+}
+
+func TestIngest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := http.NewServeMux()
+	jobs := web.Ingest[job](ctx, mux, web.WithQueue(4))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// A JSON array and JSON Lines are both accepted.
+	if code, m := post(t, ts.URL+"/items", `[{"n":1},{"n":2}]`); code != http.StatusAccepted || m["accepted"] != 2.0 {
+		t.Fatalf("array: status %d, body %v", code, m)
+	}
+	if code, m := post(t, ts.URL+"/items", "{\"n\":3}\n{\"n\":4}\n"); code != http.StatusAccepted || m["accepted"] != 2.0 {
+		t.Fatalf("jsonl: status %d, body %v", code, m)
+	}
+	for want := 1; want <= 4; want++ {
+		if got := <-jobs; got.N != want {
+			t.Errorf("want item %d, got %d", want, got.N)
+		}
+	}
+
+	if code, m := post(t, ts.URL+"/items", `{"n":5} {"n":`); code != http.StatusBadRequest || m["accepted"] != 1.0 {
+		t.Errorf("malformed: status %d, body %v", code, m)
+	}
+	<-jobs
+}
+
+func TestIngestSaturated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := http.NewServeMux()
+	_ = web.Ingest[job](ctx, mux, web.WithQueue(2)) // never consumed
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := noProxy().Post(ts.URL+"/items", "application/json", strings.NewReader(`[{"n":1},{"n":2},{"n":3},{"n":4},{"n":5}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if code, _ := post(t, ts.URL+"/items", `{"n":1}`); code != http.StatusServiceUnavailable {
+		t.Errorf("after cancel: expected 503, got %d", code)
+	}
+}
+
+func TestIngestNoQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := http.NewServeMux()
+	jobs := web.Ingest[job](ctx, mux) // no queue, and nobody reading yet
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// With no reader ready, not even one item may be accepted.
+	if code, m := post(t, ts.URL+"/items", `{"n":1}`); code != http.StatusTooManyRequests || m["accepted"] != 0.0 {
+		t.Errorf("status %d, body %v; want 429 with nothing accepted", code, m)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-jobs:
+		if ok {
+			t.Error("got an item after cancellation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream did not close after cancellation")
+	}
+}
+
+func TestIngestProcessWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := http.NewServeMux()
+	results := web.IngestProcess(ctx, mux, func(ctx context.Context, j job) (int, error) {
+		if j.N < 0 {
+			return 0, errors.New("negative")
+		}
+		return j.N * 10, nil
+	}, web.WithBlocking(), web.WithProcess(gojob.WithWorkers(4)))
+	go gojob.Drain(results)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := noProxy().Post(ts.URL+"/items?wait=true", "application/json", strings.NewReader(`[{"n":1},{"n":-1},{"n":3}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got []struct {
+		Value int    `json:"value"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(got) != 3 || got[0].Value != 10 || got[1].Error != "negative" || got[2].Value != 30 {
+		t.Errorf("unexpected response %d: %+v", resp.StatusCode, got)
+	}
+}
@@ -4,6 +4,9 @@
 //
 //	results, stats := gojob.WithStats(ctx, results)
 //	go web.Serve(ctx, stats, ":8080")
+//
+// It can also feed a pipeline over HTTP: Ingest and IngestProcess turn items
+// POSTed by clients into a source.
 package web
 
 import (
@@ -21,6 +24,7 @@ import (
 type config struct {
 	interval time.Duration
 	title    string
+	topology *gojob.Topology
 }
 
 // Option configures the dashboard.
@@ -42,6 +46,16 @@ func WithTitle(title string) Option {
 	}
 }
 
+// WithTopology adds the pipeline's stages (see gojob.WithTopology) to the
+// dashboard, with their in-flight, queued and throughput counters and the
+// bottleneck highlighted. It also serves the topology at "/topology" as JSON
+// and at "/topology.dot" as Graphviz DOT.
+func WithTopology(t *gojob.Topology) Option {
+	return func(c *config) {
+		c.topology = t
+	}
+}
+
 // Handler returns the dashboard as an http.Handler: "/" serves the page and
 // "/events" streams progress as Server-Sent Events. It observes stats and stops
 // streaming a client when that client disconnects, when the job finishes, or
@@ -62,6 +76,16 @@ func Handler(ctx context.Context, stats *gojob.Stats, opts ...Option) http.Handl
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_, _ = w.Write([]byte(page))
 	})
+	if cfg.topology != nil {
+		mux.HandleFunc("/topology", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(cfg.topology.Snapshot())
+		})
+		mux.HandleFunc("/topology.dot", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			_, _ = w.Write([]byte(cfg.topology.Snapshot().DOT()))
+		})
+	}
 	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
 		flusher, ok := w.(http.Flusher)
 		if !ok {
@@ -75,7 +99,7 @@ func Handler(ctx context.Context, stats *gojob.Stats, opts ...Option) http.Handl
 		ticker := time.NewTicker(cfg.interval)
 		defer ticker.Stop()
 
-		writeEvent(w, flusher, stats.Snapshot(), false) // send current state immediately
+		writeEvent(w, flusher, stats.Snapshot(), cfg.topology, false) // send current state immediately
 		for {
 			select {
 			case <-r.Context().Done():
@@ -83,10 +107,10 @@ func Handler(ctx context.Context, stats *gojob.Stats, opts ...Option) http.Handl
 			case <-ctx.Done():
 				return
 			case <-stats.Done():
-				writeEvent(w, flusher, stats.Snapshot(), true) // final snapshot
+				writeEvent(w, flusher, stats.Snapshot(), cfg.topology, true) // final snapshot
 				return
 			case <-ticker.C:
-				writeEvent(w, flusher, stats.Snapshot(), false)
+				writeEvent(w, flusher, stats.Snapshot(), cfg.topology, false)
 			}
 		}
 	})
@@ -115,18 +139,27 @@ type update struct {
 	Succeeded int64 `json:"succeeded"`
 	Failed    int64 `json:"failed"`
 	ElapsedMs int64 `json:"elapsed_ms"`
+	EtaMs     int64 `json:"eta_ms"`
 	Finished  bool  `json:"finished"`
+
+	Topology *gojob.TopologySnapshot `json:"topology,omitempty"`
 }
 
-func writeEvent(w http.ResponseWriter, f http.Flusher, snap gojob.Snapshot, finished bool) {
-	b, err := json.Marshal(update{
+func writeEvent(w http.ResponseWriter, f http.Flusher, snap gojob.Snapshot, topo *gojob.Topology, finished bool) {
+	u := update{
 		Total:     snap.Total,
 		Done:      snap.Done,
 		Succeeded: snap.Succeeded,
 		Failed:    snap.Failed,
 		ElapsedMs: snap.Elapsed.Milliseconds(),
+		EtaMs:     etaMs(snap.ETA),
 		Finished:  finished,
-	})
+	}
+	if topo != nil {
+		ts := topo.Snapshot()
+		u.Topology = &ts
+	}
+	b, err := json.Marshal(u)
 	if err != nil {
 		return
 	}
@@ -134,6 +167,14 @@ func writeEvent(w http.ResponseWriter, f http.Flusher, snap gojob.Snapshot, fini
 	f.Flush()
 }
 
+// etaMs converts a Snapshot ETA to milliseconds, keeping -1 for unknown.
+func etaMs(eta time.Duration) int64 {
+	if eta < 0 {
+		return -1
+	}
+	return eta.Milliseconds()
+}
+
 const indexHTML = `<!doctype html>
 <html lang="en">
 <head>
@@ -159,6 +200,12 @@ const indexHTML = `<!doctype html>
   .tile .k{font-size:11px;text-transform:uppercase;letter-spacing:.04em;color:var(--muted)}
   .tile .v{font-size:20px;font-weight:600;margin-top:3px;font-variant-numeric:tabular-nums}
   .v.ok{color:var(--ok)}.v.fail{color:var(--fail)}
+  .stages{display:flex;flex-wrap:wrap;align-items:center;gap:6px;margin-top:22px}
+  .stages:empty{display:none}
+  .stage{flex:1 1 110px;background:var(--bg);border:1px solid var(--line);border-radius:10px;padding:10px 12px;font-size:12px;color:var(--muted);font-variant-numeric:tabular-nums}
+  .stage b{display:block;font-size:14px;color:var(--fg)}
+  .stage.hot{border-color:var(--fail)}.stage.hot b{color:var(--fail)}
+  .arrow{color:var(--muted)}
   @media (max-width:480px){.grid{grid-template-columns:repeat(2,1fr)}}
 </style>
 </head>
@@ -173,10 +220,22 @@ const indexHTML = `<!doctype html>
       <div class="tile"><div class="k">Failed</div><div class="v fail" id="fail">0</div></div>
       <div class="tile"><div class="k">Rate</div><div class="v" id="rate">0/s</div></div>
     </div>
+    <div class="stages" id="stages"></div>
   </div>
 <script>
   var $=function(id){return document.getElementById(id)};
   var nf=new Intl.NumberFormat();
+  var fmtDur=function(ms){var s=Math.round(ms/1000),h=Math.floor(s/3600),m=Math.floor(s%3600/60);return h>0?h+'h'+m+'m':m>0?m+'m'+(s%60)+'s':s+'s'};
+  var esc=function(s){var d=document.createElement('div');d.textContent=s;return d.innerHTML};
+  var fmtRate=function(r){return (r>=100?Math.round(r):Math.round(r*10)/10)+'/s'};
+  var renderStages=function(t){
+    if(!t){return}
+    $('stages').innerHTML=(t.stages||[]).map(function(s,i){
+      return (i>0?'<span class="arrow">→</span>':'')+
+        '<div class="stage'+(s.name===t.bottleneck?' hot':'')+'" title="'+esc(s.kind)+(s.inputs?' ← '+esc(s.inputs.join(', ')):'')+'">'+
+        '<b>'+esc(s.name)+'</b>'+nf.format(s.in_flight)+' in flight · '+nf.format(s.queued)+' queued<br>'+fmtRate(s.throughput)+'</div>';
+    }).join('');
+  };
   var es=new EventSource('/events');
   es.onmessage=function(e){
     var d=JSON.parse(e.data);
@@ -188,10 +247,11 @@ const indexHTML = `<!doctype html>
     $('ok').textContent=nf.format(d.succeeded);
     $('fail').textContent=nf.format(d.failed);
     var rate=d.elapsed_ms>0?done/(d.elapsed_ms/1000):0;
-    $('rate').textContent=(rate>=100?Math.round(rate):Math.round(rate*10)/10)+'/s';
+    $('rate').textContent=fmtRate(rate);
+    renderStages(d.topology);
     var st=$('status');
     if(d.finished){st.textContent='Completed';st.className='badge done'}
-    else{st.textContent='Running';st.className='badge'}
+    else{st.textContent=d.eta_ms>=0?'Running · '+fmtDur(d.eta_ms)+' left':'Running';st.className='badge'}
   };
   es.onerror=function(){var st=$('status');if(st.textContent!=='Completed'){st.textContent='Disconnected';st.className='badge'}};
 </script>
@@ -99,6 +99,44 @@ func TestDashboardEvents(t *testing.T) {
 	}
 }
 
+func TestDashboardTopology(t *testing.T) {
+	ctx, topo := gojob.WithTopology(context.Background())
+	results := gojob.Process(ctx, gojob.From(ctx, 1, 2), func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}, gojob.WithName("fetch"))
+	results, stats := gojob.WithStats(ctx, results)
+	gojob.Drain(results)
+
+	ts := httptest.NewServer(web.Handler(ctx, stats, web.WithTopology(topo)))
+	defer ts.Close()
+	get := func(path string) string {
+		resp, err := noProxy().Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s status = %d", path, resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	var snap gojob.TopologySnapshot
+	if err := json.Unmarshal([]byte(get("/topology")), &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Stages) != 2 || snap.Stages[0].Name != "fetch" || snap.Stages[1].Out != 2 {
+		t.Errorf("unexpected topology: %+v", snap)
+	}
+	if dot := get("/topology.dot"); !strings.Contains(dot, `"fetch" -> "stats-1";`) {
+		t.Errorf("unexpected DOT:\n%s", dot)
+	}
+	if events := get("/events"); !strings.Contains(events, `"topology":{"stages":[{"name":"fetch"`) {
+		t.Errorf("events should carry the topology: %s", events)
+	}
+}
+
 func TestServeShutsDown(t *testing.T) {
 	// Grab a free port, then let Serve bind it.
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
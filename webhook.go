@@ -0,0 +1,417 @@
+package gojob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type httpConfig struct {
+	batch    int
+	linger   time.Duration
+	header   http.Header
+	ndjson   bool
+	retries  int
+	backoff  BackoffFunc
+	spillDir string
+	client   *http.Client
+}
+
+// HTTPOption configures WriteHTTP.
+type HTTPOption func(*httpConfig)
+
+// WithBatch sends up to n results per request, waiting at most linger after
+// the first result of a batch for the rest (default 100 results, 1s).
+func WithBatch(n int, linger time.Duration) HTTPOption {
+	return func(c *httpConfig) {
+		if n > 0 {
+			c.batch = n
+		}
+		if linger > 0 {
+			c.linger = linger
+		}
+	}
+}
+
+// WithHeaders adds h to every request, e.g. for authentication.
+func WithHeaders(h http.Header) HTTPOption {
+	return func(c *httpConfig) {
+		for k, vs := range h {
+			for _, v := range vs {
+				c.header.Add(k, v)
+			}
+		}
+	}
+}
+
+// WithNDJSON sends each batch as newline-delimited JSON (Content-Type
+// application/x-ndjson) instead of a JSON array.
+func WithNDJSON() HTTPOption {
+	return func(c *httpConfig) {
+		c.ndjson = true
+	}
+}
+
+// WithHTTPRetry sets how many times a batch is attempted (>= 1) and the
+// backoff between attempts (default 5 attempts, ExpBackoff(500ms, 30s)).
+// Network errors, 408, 429 and 5xx responses are retried; other responses
+// fail the write.
+func WithHTTPRetry(maxAttempts int, backoff BackoffFunc) HTTPOption {
+	return func(c *httpConfig) {
+		if maxAttempts > 0 {
+			c.retries = maxAttempts
+		}
+		c.backoff = backoff
+	}
+}
+
+// WithSpillDir keeps the job running while the endpoint is down: a batch that
+// exhausts its retries is written to a file in dir, and until the endpoint
+// accepts a batch again, later batches go straight to disk after a single
+// attempt to deliver the oldest spilled one. Spilled batches are sent, oldest
+// first, once the endpoint recovers; those still on disk when WriteHTTP
+// returns are sent by the next WriteHTTP with the same dir. A healthy but
+// slow endpoint still applies backpressure rather than filling the disk.
+func WithSpillDir(dir string) HTTPOption {
+	return func(c *httpConfig) {
+		c.spillDir = dir
+	}
+}
+
+// WithHTTPClient sets the client requests are made with (default
+// http.DefaultClient).
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(c *httpConfig) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WriteHTTP POSTs the stream to url in batches, each a JSON array of results
+// (or NDJSON, see WithNDJSON) in the shape WriteJSONL writes, so a job can feed
+// an ingestion service directly instead of through a separate shipper.
+//
+//	err := gojob.WriteHTTP(ctx, "https://ingest.example.com/v1/results", results,
+//		gojob.WithBatch(500, 2*time.Second), gojob.WithNDJSON(),
+//		gojob.WithHeaders(http.Header{"Authorization": {"Bearer " + token}}),
+//		gojob.WithSpillDir("spill"))
+//
+// Batches are sent by a single goroutine, one at a time, with retries, so a
+// slow endpoint applies backpressure to the stream. Without WithSpillDir a
+// batch that exhausts its retries fails the write.
+//
+// WriteHTTP returns once every batch has been delivered (or spilled), with the
+// first error, the ctx error, or the source errors collected in ctx (see
+// WithErrors). With WithSpillDir, batches not yet delivered when ctx is
+// cancelled are spilled too, so no result taken from in is lost.
+func WriteHTTP[T any](ctx context.Context, url string, in <-chan Result[T], opts ...HTTPOption) error {
+	cfg := httpConfig{
+		batch:   100,
+		linger:  time.Second,
+		header:  http.Header{},
+		retries: 5,
+		backoff: ExpBackoff(500*time.Millisecond, 30*time.Second),
+		client:  http.DefaultClient,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	s := &httpSink{cfg: cfg, url: url}
+	if cfg.spillDir != "" {
+		if err := os.MkdirAll(cfg.spillDir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	queue := make(chan []byte, 1)
+	sent := make(chan error, 1)
+	go func() { sent <- s.run(ctx, queue) }()
+
+	// stop spills whatever has not been handed to the sender.
+	stop := func(err error, pending ...[]byte) error {
+		select {
+		case body := <-queue:
+			pending = append(pending, body)
+		default:
+		}
+		for _, body := range pending {
+			if len(body) > 0 && s.cfg.spillDir != "" {
+				if serr := s.spill(body); serr != nil && err == nil {
+					err = serr
+				}
+			}
+		}
+		return err
+	}
+
+	var (
+		batch  []Result[T]
+		linger <-chan time.Time
+		timer  *time.Timer
+	)
+	// enqueue hands the batch to the sender; it returns false if the write
+	// has to stop.
+	enqueue := func(body []byte) (bool, error) {
+		select {
+		case queue <- body:
+			return true, nil
+		case err := <-sent:
+			return false, stop(err, body)
+		case <-ctx.Done():
+			err := stop(ctx.Err(), body)
+			<-sent
+			return false, err
+		}
+	}
+	flush := func() (bool, error) {
+		if timer != nil {
+			timer.Stop()
+			timer, linger = nil, nil
+		}
+		if len(batch) == 0 {
+			return true, nil
+		}
+		body, err := s.encode(len(batch), func(i int) any { return batch[i] })
+		clear(batch)
+		batch = batch[:0]
+		if err != nil {
+			return false, err
+		}
+		return enqueue(body)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			body, _ := s.encode(len(batch), func(i int) any { return batch[i] })
+			err := stop(ctx.Err(), body)
+			<-sent // the sender spills the batch in flight
+			return err
+		case err := <-sent:
+			// The sender only stops early on a permanent failure.
+			body, _ := s.encode(len(batch), func(i int) any { return batch[i] })
+			return stop(err, body)
+		case <-linger:
+			timer, linger = nil, nil
+			if ok, err := flush(); !ok || err != nil {
+				return err
+			}
+		case r, ok := <-in:
+			if !ok {
+				if ok, err := flush(); !ok || err != nil {
+					return err
+				}
+				close(queue)
+				if err := <-sent; err != nil {
+					return err
+				}
+				return sourceErr(ctx)
+			}
+			batch = append(batch, r)
+			if len(batch) == 1 {
+				timer = time.NewTimer(cfg.linger)
+				linger = timer.C
+			}
+			if len(batch) >= cfg.batch {
+				if ok, err := flush(); !ok || err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+type httpSink struct {
+	cfg httpConfig
+	url string
+	seq atomic.Int64
+}
+
+// permanentError is a response that retrying will not fix.
+type permanentError struct{ status string }
+
+func (e *permanentError) Error() string { return "gojob: endpoint rejected batch: " + e.status }
+
+func (s *httpSink) encode(n int, item func(int) any) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if !s.cfg.ndjson {
+		buf.WriteByte('[')
+	}
+	enc := json.NewEncoder(&buf)
+	for i := 0; i < n; i++ {
+		if i > 0 && !s.cfg.ndjson {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(item(i)); err != nil {
+			return nil, err
+		}
+	}
+	if !s.cfg.ndjson {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
+
+// run sends the batches from queue until it is closed, then makes a last
+// attempt at the spilled ones. It returns early only on a permanent failure,
+// a failure without a spill dir, or cancellation; with a spill dir, the batch
+// in flight is spilled first.
+func (s *httpSink) run(ctx context.Context, queue <-chan []byte) error {
+	// down is set once a batch was spilled, and cleared once the spilled
+	// batches are delivered; meanwhile the endpoint gets one attempt per
+	// batch instead of a full round of retries.
+	down := false
+	for {
+		attempts := s.cfg.retries
+		if down {
+			attempts = 1
+		}
+		if err := s.sendSpilled(ctx, attempts); err != nil {
+			if !s.retryable(err) {
+				return err
+			}
+		} else {
+			down = false
+		}
+		var body []byte
+		select {
+		case b, ok := <-queue:
+			if !ok {
+				return s.sendSpilled(ctx, s.cfg.retries)
+			}
+			body = b
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if down {
+			// The spilled batches could not be delivered just now either.
+			if err := s.spill(body); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.post(ctx, body, s.cfg.retries); err != nil {
+			if s.cfg.spillDir == "" || !(s.retryable(err) || ctx.Err() != nil) {
+				return err
+			}
+			if err := s.spill(body); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			down = true
+		}
+	}
+}
+
+// retryable reports whether err may go away later, so the batch is worth
+// keeping on disk.
+func (s *httpSink) retryable(err error) bool {
+	var perm *permanentError
+	return !errors.As(err, &perm) && !errors.Is(err, context.Canceled)
+}
+
+// sendSpilled sends the spilled batches, oldest first, each with up to
+// attempts attempts, stopping at the first that cannot be delivered.
+func (s *httpSink) sendSpilled(ctx context.Context, attempts int) error {
+	if s.cfg.spillDir == "" {
+		return nil
+	}
+	names, err := filepath.Glob(filepath.Join(s.cfg.spillDir, "batch-*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		body, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := s.post(ctx, body, attempts); err != nil {
+			if !s.retryable(err) && ctx.Err() == nil {
+				// Set it aside, or it would block the batches behind it forever.
+				_ = os.Rename(name, name+".rejected")
+				return fmt.Errorf("gojob: %s: %w", name, err)
+			}
+			return fmt.Errorf("gojob: %d batches left in %s: %w", len(names)-i, s.cfg.spillDir, err)
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spill writes a batch to the spill dir; the name sorts in spill order.
+func (s *httpSink) spill(body []byte) error {
+	name := filepath.Join(s.cfg.spillDir, fmt.Sprintf("batch-%020d-%06d.json", time.Now().UnixNano(), s.seq.Add(1)))
+	if err := os.WriteFile(name+".tmp", body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// post sends one batch, making up to attempts attempts.
+func (s *httpSink) post(ctx context.Context, body []byte, attempts int) error {
+	for attempt := 1; ; attempt++ {
+		err := s.send(ctx, body)
+		if err == nil || !s.retryable(err) || attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+		if s.cfg.backoff != nil {
+			if d := s.cfg.backoff(attempt); d > 0 {
+				t := time.NewTimer(d)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+func (s *httpSink) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{status: err.Error()}
+	}
+	for k, vs := range s.cfg.header {
+		req.Header[k] = vs
+	}
+	if s.cfg.ndjson {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("gojob: endpoint returned %s", resp.Status)
+	default:
+		return &permanentError{status: strings.TrimSpace(resp.Status)}
+	}
+}
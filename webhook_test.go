@@ -0,0 +1,217 @@
+package gojob_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WangYihang/gojob"
+)
+
+// ingestServer counts the results it receives; while down is set it answers
+// 503.
+type ingestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	batches  []int
+	header   http.Header
+	down     atomic.Bool
+	requests atomic.Int64
+}
+
+func newIngestServer(t *testing.T) *ingestServer {
+	s := &ingestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.down.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		n := 0
+		if r.Header.Get("Content-Type") == "application/x-ndjson" {
+			n = strings.Count(string(body), "\n")
+		} else {
+			var items []map[string]any
+			if err := json.Unmarshal(body, &items); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			n = len(items)
+		}
+		s.mu.Lock()
+		s.batches = append(s.batches, n)
+		s.header = r.Header.Clone()
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ingestServer) received() (total int, batches []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.batches {
+		total += n
+	}
+	return total, append([]int(nil), s.batches...)
+}
+
+func TestWriteHTTPBatches(t *testing.T) {
+	ctx := context.Background()
+	srv := newIngestServer(t)
+	err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 25),
+		gojob.WithBatch(10, time.Minute), gojob.WithHeaders(http.Header{"Authorization": {"Bearer x"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, batches := srv.received()
+	if total != 25 || len(batches) != 3 || batches[0] != 10 {
+		t.Errorf("got batches %v, want 10, 10, 5", batches)
+	}
+	if srv.header.Get("Authorization") != "Bearer x" || srv.header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", srv.header)
+	}
+}
+
+func TestWriteHTTPLingerNDJSON(t *testing.T) {
+	ctx := context.Background()
+	srv := newIngestServer(t)
+	in := make(chan gojob.Result[int])
+	done := make(chan error)
+	go func() {
+		done <- gojob.WriteHTTP(ctx, srv.URL, in, gojob.WithBatch(100, 10*time.Millisecond), gojob.WithNDJSON())
+	}()
+	in <- gojob.Result[int]{Value: 1}
+	in <- gojob.Result[int]{Value: 2}
+	deadline := time.Now().Add(5 * time.Second)
+	for total, _ := srv.received(); total != 2; total, _ = srv.received() {
+		if time.Now().After(deadline) {
+			t.Fatal("a partial batch was never sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteHTTPRetry(t *testing.T) {
+	ctx := context.Background()
+	srv := newIngestServer(t)
+	srv.down.Store(true)
+	time.AfterFunc(50*time.Millisecond, func() { srv.down.Store(false) })
+	err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 5), gojob.WithHTTPRetry(100, gojob.ExpBackoff(5*time.Millisecond, 20*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := srv.received(); total != 5 {
+		t.Errorf("got %d results, want 5", total)
+	}
+}
+
+func TestWriteHTTPPermanentError(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 5), gojob.WithHTTPRetry(3, gojob.NoBackoff()))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a 403 error, got %v", err)
+	}
+}
+
+func TestWriteHTTPSpill(t *testing.T) {
+	ctx := context.Background()
+	srv := newIngestServer(t)
+	srv.down.Store(true)
+	dir := filepath.Join(t.TempDir(), "spill")
+	opts := []gojob.HTTPOption{gojob.WithBatch(2, time.Minute), gojob.WithSpillDir(dir), gojob.WithHTTPRetry(2, gojob.NoBackoff())}
+
+	// The endpoint is down for the whole run: nothing stalls, everything spills.
+	err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 9), opts...)
+	if err == nil || !strings.Contains(err.Error(), "batches left") {
+		t.Fatalf("expected undelivered batches to be reported, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "batch-*.json")); len(files) != 5 {
+		t.Fatalf("got %d spilled batches, want 5", len(files))
+	}
+
+	// Once it is back, the next run delivers the spilled batches as well.
+	srv.down.Store(false)
+	if err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 1), opts...); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := srv.received(); total != 10 {
+		t.Errorf("got %d results, want 10", total)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("spill dir not emptied: %d files left", len(files))
+	}
+}
+
+func TestWriteHTTPSlowEndpointDoesNotSpill(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var received, spilled atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if files, _ := filepath.Glob(filepath.Join(dir, "batch-*.json")); len(files) > 0 {
+			spilled.Store(int64(len(files)))
+		}
+		time.Sleep(5 * time.Millisecond)
+		received.Add(1)
+	}))
+	defer srv.Close()
+	// A healthy endpoint slower than the producer slows the producer down.
+	if err := gojob.WriteHTTP(ctx, srv.URL, intResults(ctx, 20), gojob.WithBatch(1, time.Minute), gojob.WithSpillDir(dir)); err != nil {
+		t.Fatal(err)
+	}
+	if received.Load() != 20 || spilled.Load() != 0 {
+		t.Errorf("got %d batches, %d spilled along the way; want 20, none spilled", received.Load(), spilled.Load())
+	}
+}
+
+func TestWriteHTTPCancelSpills(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := newIngestServer(t)
+	srv.down.Store(true)
+	dir := t.TempDir()
+	in := make(chan gojob.Result[int])
+	done := make(chan error)
+	go func() {
+		done <- gojob.WriteHTTP(ctx, srv.URL, in, gojob.WithBatch(2, time.Minute), gojob.WithSpillDir(dir),
+			gojob.WithHTTPRetry(1000, gojob.ExpBackoff(time.Millisecond, 10*time.Millisecond)))
+	}()
+	for i := range 5 {
+		in <- gojob.Result[int]{Value: i}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	total := 0
+	files, _ := filepath.Glob(filepath.Join(dir, "batch-*.json"))
+	for _, f := range files {
+		var items []any
+		b, _ := os.ReadFile(f)
+		if err := json.Unmarshal(b, &items); err != nil {
+			t.Fatal(err)
+		}
+		total += len(items)
+	}
+	if total != 5 {
+		t.Errorf("got %d spilled results, want all 5", total)
+	}
+}